@@ -0,0 +1,193 @@
+package effect
+
+import (
+	"github.com/gotracker/gomixing/fft"
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// DefaultConvolutionBlockSize is the default partition size, in samples, of a convolution reverb
+const DefaultConvolutionBlockSize = 256
+
+// Convolution is a convolution reverb that applies an impulse response to the mix using
+// uniformly partitioned FFT convolution (overlap-save). The output is delayed by one partition.
+// A mono impulse response is applied to every channel; otherwise each output channel uses the
+// impulse response channel with the same index, wrapping around for buffers with more channels.
+// The impulse response should be at the same sample rate as the mix.
+type Convolution struct {
+	// Wet is the level of the convolved signal
+	Wet float64
+	// Dry is the level of the original signal
+	Dry float64
+
+	blockSize int
+	plan      *fft.Plan
+	irParts   [][][]complex128
+	channels  [volume.MaxChannels]convolutionChannel
+	dry       [volume.MaxChannels][]float64
+	pos       int
+	p         params
+}
+
+type convolutionChannel struct {
+	input   []float64
+	output  []float64
+	fdl     [][]complex128
+	fdlHead int
+	scratch []complex128
+	accum   []complex128
+}
+
+// NewConvolution returns a convolution reverb for the impulse response provided,
+// partitioned into blocks of `blockSize` samples (rounded up to a power of two)
+func NewConvolution(ir *ImpulseResponse, blockSize int) *Convolution {
+	if blockSize <= 0 {
+		blockSize = DefaultConvolutionBlockSize
+	}
+	blockSize = fft.NextPowerOfTwo(blockSize)
+	n := blockSize * 2
+
+	c := &Convolution{
+		Wet:       1,
+		Dry:       0,
+		blockSize: blockSize,
+		plan:      fft.NewPlan(n),
+	}
+
+	numParts := (ir.Len() + blockSize - 1) / blockSize
+	if numParts < 1 {
+		numParts = 1
+	}
+	c.irParts = make([][][]complex128, len(ir.Channels))
+	for ch, data := range ir.Channels {
+		parts := make([][]complex128, numParts)
+		for p := range parts {
+			part := make([]complex128, n)
+			for i := 0; i < blockSize; i++ {
+				if idx := p*blockSize + i; idx < len(data) {
+					part[i] = complex(data[idx], 0)
+				}
+			}
+			c.plan.Forward(part)
+			parts[p] = part
+		}
+		c.irParts[ch] = parts
+	}
+
+	for ch := range c.channels {
+		cc := &c.channels[ch]
+		cc.input = make([]float64, n)
+		cc.output = make([]float64, blockSize)
+		cc.fdl = make([][]complex128, numParts)
+		for p := range cc.fdl {
+			cc.fdl[p] = make([]complex128, n)
+		}
+		cc.scratch = make([]complex128, n)
+		cc.accum = make([]complex128, n)
+		c.dry[ch] = make([]float64, blockSize)
+	}
+
+	c.p = params{effect: "convolution"}
+	c.p.add("wet", 0, 4, 1, &c.Wet)
+	c.p.add("dry", 0, 1, 0, &c.Dry)
+	return c
+}
+
+// Process applies the convolution to the mix buffer in place
+func (c *Convolution) Process(buf mixing.MixBuffer) {
+	if len(c.irParts) == 0 {
+		return
+	}
+	for i := range buf {
+		samp := &buf[i]
+		for ch := 0; ch < samp.Channels; ch++ {
+			cc := &c.channels[ch]
+			x := float64(samp.StaticMatrix[ch])
+			cc.input[c.blockSize+c.pos] = x
+			wet := cc.output[c.pos]
+			dry := c.dry[ch][c.pos]
+			c.dry[ch][c.pos] = x
+			samp.StaticMatrix[ch] = volume.Volume(dry*c.Dry + wet*c.Wet)
+		}
+		c.pos++
+		if c.pos >= c.blockSize {
+			c.pos = 0
+			for ch := 0; ch < samp.Channels; ch++ {
+				c.processBlock(ch)
+			}
+		}
+	}
+}
+
+// processBlock convolves the most recent input block of a channel with its impulse response
+func (c *Convolution) processBlock(ch int) {
+	cc := &c.channels[ch]
+	parts := c.irParts[ch%len(c.irParts)]
+	n := len(cc.input)
+
+	// the newest spectrum goes to the head of the frequency-domain delay line
+	cc.fdlHead--
+	if cc.fdlHead < 0 {
+		cc.fdlHead = len(cc.fdl) - 1
+	}
+	spectrum := cc.fdl[cc.fdlHead]
+	for i, v := range cc.input {
+		spectrum[i] = complex(v, 0)
+	}
+	c.plan.Forward(spectrum)
+
+	for i := range cc.accum {
+		cc.accum[i] = 0
+	}
+	for p, h := range parts {
+		x := cc.fdl[(cc.fdlHead+p)%len(cc.fdl)]
+		for i := range cc.accum {
+			cc.accum[i] += x[i] * h[i]
+		}
+	}
+	copy(cc.scratch, cc.accum)
+	c.plan.Inverse(cc.scratch)
+
+	// overlap-save: the last block of the circular convolution is valid
+	for i := 0; i < c.blockSize; i++ {
+		cc.output[i] = real(cc.scratch[c.blockSize+i])
+	}
+	copy(cc.input[:c.blockSize], cc.input[c.blockSize:n])
+}
+
+// Reset clears the convolution's input history
+func (c *Convolution) Reset() {
+	for ch := range c.channels {
+		cc := &c.channels[ch]
+		clearFloats(cc.input)
+		clearFloats(cc.output)
+		for _, s := range cc.fdl {
+			for i := range s {
+				s[i] = 0
+			}
+		}
+		cc.fdlHead = 0
+		clearFloats(c.dry[ch])
+	}
+	c.pos = 0
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (c *Convolution) Latency() int {
+	return c.blockSize
+}
+
+// Parameters returns the descriptions of the parameters of the convolution reverb
+func (c *Convolution) Parameters() []mixing.ParameterInfo {
+	return c.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (c *Convolution) GetParameter(name string) (float64, error) {
+	return c.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (c *Convolution) SetParameter(name string, value float64) error {
+	return c.p.set(name, value)
+}
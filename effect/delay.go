@@ -0,0 +1,164 @@
+package effect
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+const (
+	// DefaultDelayGlide is the default time in samples over which delay time changes are smoothed
+	DefaultDelayGlide = 2048
+	maxDelayFeedback  = 0.99
+)
+
+// Delay is a multichannel delay/echo effect with filtered feedback and an optional ping-pong mode.
+// The delay time is smoothed when it changes, gliding the pitch rather than jumping and clicking.
+type Delay struct {
+	// Time is the delay time in samples
+	Time float64
+	// Feedback is the amount of the delayed signal fed back into the delay line (0-0.99)
+	Feedback float64
+	// LowPass is the cutoff frequency in Hz of the filter in the feedback loop
+	LowPass float64
+	// Wet is the level of the delayed signal
+	Wet float64
+	// Dry is the level of the original signal
+	Dry float64
+	// PingPong bounces the echoes between the left and right channels of each channel pair
+	PingPong bool
+
+	sampleRate float64
+	lines      [volume.MaxChannels]delayLine
+	lp         [volume.MaxChannels]float64
+	time       float64
+	glide      float64
+	primed     bool
+	p          params
+}
+
+// NewDelay returns a delay effect capable of delaying up to `maxSeconds` seconds
+func NewDelay(sampleRate float64, maxSeconds float64) *Delay {
+	capacity := int(math.Ceil(maxSeconds*sampleRate)) + 2
+	d := &Delay{
+		Time:       sampleRate / 4,
+		Feedback:   0.5,
+		LowPass:    sampleRate / 2,
+		Wet:        0.5,
+		Dry:        1,
+		sampleRate: sampleRate,
+	}
+	for c := range d.lines {
+		d.lines[c] = newDelayLine(capacity)
+	}
+	d.SetGlide(DefaultDelayGlide)
+
+	maxTime := float64(capacity - 2)
+	d.p = params{effect: "delay"}
+	d.p.add("time", 1, maxTime, sampleRate/4, &d.Time)
+	d.p.add("feedback", 0, maxDelayFeedback, 0.5, &d.Feedback)
+	d.p.add("lowpass", 20, sampleRate/2, sampleRate/2, &d.LowPass)
+	d.p.add("wet", 0, 1, 0.5, &d.Wet)
+	d.p.add("dry", 0, 1, 1, &d.Dry)
+	d.p.addBool("pingpong", false, &d.PingPong)
+	return d
+}
+
+// SetGlide sets the time constant, in samples, over which delay time changes are smoothed
+func (d *Delay) SetGlide(samples float64) {
+	if samples <= 0 {
+		d.glide = 1
+		return
+	}
+	d.glide = 1 - math.Exp(-1/samples)
+}
+
+// SetTimeMilliseconds sets the delay time in milliseconds
+func (d *Delay) SetTimeMilliseconds(ms float64) {
+	d.Time = ms * d.sampleRate / 1000
+}
+
+// SetTimeTempo sets the delay time to a fraction of a beat at a tempo in beats per minute,
+// such as 0.75 for a dotted eighth note
+func (d *Delay) SetTimeTempo(bpm float64, beats float64) {
+	if bpm <= 0 {
+		return
+	}
+	d.Time = beats * 60 / bpm * d.sampleRate
+}
+
+// SetTimeRows sets the delay time to a number of tracker rows at the tempo and speed provided
+func (d *Delay) SetTimeRows(rows float64, tempo int, speed int) {
+	if tempo <= 0 {
+		return
+	}
+	// a tracker tick lasts 2.5/tempo seconds
+	d.Time = rows * float64(speed) * 2.5 / float64(tempo) * d.sampleRate
+}
+
+// Process applies the delay to the mix buffer in place
+func (d *Delay) Process(buf mixing.MixBuffer) {
+	if !d.primed {
+		d.time = d.Time
+		d.primed = true
+	}
+	feedback := math.Min(math.Max(d.Feedback, 0), maxDelayFeedback)
+	lpCoeff := 1 - math.Exp(-2*math.Pi*math.Min(d.LowPass, d.sampleRate/2)/d.sampleRate)
+
+	for i := range buf {
+		d.time += (d.Time - d.time) * d.glide
+		samp := &buf[i]
+
+		var delayed [volume.MaxChannels]float64
+		for c := 0; c < samp.Channels; c++ {
+			delayed[c] = d.lines[c].readFrac(d.time)
+			d.lp[c] += (delayed[c] - d.lp[c]) * lpCoeff
+		}
+
+		for c := 0; c < samp.Channels; c++ {
+			x := float64(samp.StaticMatrix[c])
+			if d.PingPong && samp.Channels >= 2 {
+				// the left line is fed the mono input, then each line feeds the opposite one
+				other := c ^ 1
+				in := 0.0
+				if c&1 == 0 {
+					in = (x + float64(samp.StaticMatrix[other])) / 2
+				}
+				d.lines[c].write(in + d.lp[other]*feedback)
+			} else {
+				d.lines[c].write(x + d.lp[c]*feedback)
+			}
+			samp.StaticMatrix[c] = volume.Volume(x*d.Dry + delayed[c]*d.Wet)
+		}
+	}
+}
+
+// Reset clears the delay lines
+func (d *Delay) Reset() {
+	for c := range d.lines {
+		d.lines[c].reset()
+		d.lp[c] = 0
+	}
+	d.primed = false
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (d *Delay) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the delay
+func (d *Delay) Parameters() []mixing.ParameterInfo {
+	return d.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (d *Delay) GetParameter(name string) (float64, error) {
+	return d.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (d *Delay) SetParameter(name string, value float64) error {
+	return d.p.set(name, value)
+}
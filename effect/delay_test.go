@@ -0,0 +1,123 @@
+package effect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+func TestDelayTapLandsOnExactSample(t *testing.T) {
+	for _, delay := range []float64{1, 37, 100, 4800} {
+		d := NewDelay(48000, 1)
+		d.Time = delay
+		d.Feedback = 0
+		d.Dry = 0
+		d.Wet = 1
+		buf := stereoImpulse(int(delay) * 2)
+		d.Process(buf)
+		for i, s := range buf {
+			want := volume.Volume(0)
+			if i == int(delay) {
+				want = 1
+			}
+			if s.StaticMatrix[0] != want || s.StaticMatrix[1] != want {
+				t.Fatalf("delay %v: sample %d got %v, want %v", delay, i, s.StaticMatrix[:2], want)
+			}
+		}
+	}
+}
+
+func TestDelayFeedback(t *testing.T) {
+	const delay = 100
+	d := NewDelay(48000, 1)
+	d.Time = delay
+	d.Feedback = 0.5
+	d.Dry = 0
+	d.Wet = 1
+	buf := stereoImpulse(delay * 5)
+	d.Process(buf)
+
+	// the feedback filter has unity DC gain, so each echo sums to the feedback level raised to its index
+	for echo := 1; echo <= 3; echo++ {
+		var sum float64
+		for _, s := range buf[echo*delay-delay/2 : echo*delay+delay/2] {
+			sum += float64(s.StaticMatrix[0])
+		}
+		if want := math.Pow(0.5, float64(echo-1)); math.Abs(sum-want) > 1e-3 {
+			t.Errorf("echo %d: got %v, want %v", echo, sum, want)
+		}
+	}
+}
+
+func TestDelayPingPong(t *testing.T) {
+	const delay = 100
+	d := NewDelay(48000, 1)
+	d.Time = delay
+	d.Feedback = 0.5
+	d.Dry = 0
+	d.Wet = 1
+	d.PingPong = true
+	d.LowPass = 24000
+	buf := stereoImpulse(delay * 6)
+	d.Process(buf)
+
+	for echo := 1; echo <= 4; echo++ {
+		var energy [2]float64
+		for _, s := range buf[echo*delay-delay/2 : echo*delay+delay/2] {
+			for c := range energy {
+				energy[c] += float64(s.StaticMatrix[c] * s.StaticMatrix[c])
+			}
+		}
+		// the first echo is on the left, then they alternate
+		loud, quiet := 0, 1
+		if echo%2 == 0 {
+			loud, quiet = 1, 0
+		}
+		if energy[loud] == 0 || energy[quiet] != 0 {
+			t.Errorf("echo %d: got energy %v, want it all in channel %d", echo, energy, loud)
+		}
+	}
+}
+
+func TestDelayTimeHelpers(t *testing.T) {
+	d := NewDelay(48000, 1)
+	d.SetTimeMilliseconds(250)
+	if d.Time != 12000 {
+		t.Errorf("SetTimeMilliseconds(250): got %v, want 12000", d.Time)
+	}
+	d.SetTimeTempo(120, 0.75)
+	if d.Time != 18000 {
+		t.Errorf("SetTimeTempo(120, 0.75): got %v, want 18000", d.Time)
+	}
+	// a row at speed 6, tempo 125 is 6 ticks of 20ms
+	d.SetTimeRows(1, 125, 6)
+	if math.Abs(d.Time-5760) > 1e-9 {
+		t.Errorf("SetTimeRows(1, 125, 6): got %v, want 5760", d.Time)
+	}
+	d.SetTimeRows(1, 0, 6)
+	if math.Abs(d.Time-5760) > 1e-9 {
+		t.Errorf("SetTimeRows with tempo 0 changed the time to %v", d.Time)
+	}
+}
+
+func TestDelayGlide(t *testing.T) {
+	const glide = 1000
+	d := NewDelay(48000, 1)
+	d.Time = 100
+	d.SetGlide(glide)
+	d.Process(stereoImpulse(1))
+
+	d.Time = 200
+	d.Process(stereoImpulse(glide))
+	if got, want := d.time, 200-100*math.Exp(-1); math.Abs(got-want) > 1e-6 {
+		t.Errorf("delay time after one glide time constant: got %v, want %v", got, want)
+	}
+
+	d.SetGlide(0)
+	d.Time = 50
+	d.Process(stereoImpulse(1))
+	if d.time != 50 {
+		t.Errorf("delay time without glide: got %v, want 50", d.time)
+	}
+}
@@ -0,0 +1,76 @@
+package effect
+
+// delayLine is a fixed-capacity circular delay line
+type delayLine struct {
+	buf []float64
+	idx int
+}
+
+func newDelayLine(capacity int) delayLine {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return delayLine{
+		buf: make([]float64, capacity),
+	}
+}
+
+// write pushes a sample into the delay line
+func (d *delayLine) write(x float64) {
+	d.buf[d.idx] = x
+	d.idx++
+	if d.idx >= len(d.buf) {
+		d.idx = 0
+	}
+}
+
+// read returns the sample written `delay` samples ago, where a delay of 1 is the last sample written
+func (d *delayLine) read(delay int) float64 {
+	if delay < 1 {
+		delay = 1
+	} else if delay > len(d.buf) {
+		delay = len(d.buf)
+	}
+	i := d.idx - delay
+	if i < 0 {
+		i += len(d.buf)
+	}
+	return d.buf[i]
+}
+
+// process pushes a sample into the delay line and returns the sample from `delay` samples ago
+func (d *delayLine) process(x float64, delay int) float64 {
+	if delay <= 0 {
+		return x
+	}
+	d.write(x)
+	return d.read(delay + 1)
+}
+
+func (d *delayLine) reset() {
+	clearFloats(d.buf)
+	d.idx = 0
+}
+
+func clearFloats(buf []float64) {
+	for i := range buf {
+		buf[i] = 0
+	}
+}
+
+// readFrac returns the sample from `delay` samples ago, linearly interpolating fractional delays
+func (d *delayLine) readFrac(delay float64) float64 {
+	if delay < 1 {
+		delay = 1
+	} else if limit := float64(len(d.buf) - 1); delay > limit {
+		delay = limit
+	}
+	i := int(delay)
+	t := delay - float64(i)
+	a := d.read(i)
+	if t == 0 {
+		return a
+	}
+	b := d.read(i + 1)
+	return a + (b-a)*t
+}
@@ -0,0 +1,475 @@
+package effect
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Detection is how a dynamics processor measures the level of a multichannel signal
+type Detection uint8
+
+const (
+	// DetectionLinked measures the loudest channel and applies the same gain to all of them,
+	// preserving the stereo image
+	DetectionLinked = Detection(iota)
+	// DetectionPerChannel measures and processes every channel independently
+	DetectionPerChannel
+)
+
+// minLevelDB is the level in dB that silence is measured as
+const minLevelDB = -200.0
+
+// sidechain is an optional external signal used for level detection instead of the processed signal
+type sidechain struct {
+	key mixing.MixBuffer
+}
+
+// SetSidechain sets an external signal to measure levels from during the next call to Process.
+// It must be at least as long as the buffer being processed; it is cleared once it has been used
+func (s *sidechain) SetSidechain(key mixing.MixBuffer) {
+	s.key = key
+}
+
+// keyLevels returns the absolute level of each channel of the detection signal at a position
+func (s *sidechain) keyLevels(buf mixing.MixBuffer, i int) [volume.MaxChannels]float64 {
+	samp := buf[i]
+	if i < len(s.key) {
+		if k := s.key[i]; k.Channels != 0 {
+			samp = k.ToChannels(buf[i].Channels)
+		} else {
+			samp = volume.Matrix{Channels: buf[i].Channels}
+		}
+	}
+	var levels [volume.MaxChannels]float64
+	for c := 0; c < samp.Channels; c++ {
+		levels[c] = math.Abs(float64(samp.StaticMatrix[c]))
+	}
+	return levels
+}
+
+// detect returns the levels to use for each gain state: the loudest channel for linked detection,
+// otherwise each channel's own level
+func detect(levels [volume.MaxChannels]float64, channels int, detection Detection) [volume.MaxChannels]float64 {
+	if detection != DetectionLinked {
+		return levels
+	}
+	peak := 0.0
+	for c := 0; c < channels; c++ {
+		peak = math.Max(peak, levels[c])
+	}
+	for c := 0; c < channels; c++ {
+		levels[c] = peak
+	}
+	return levels
+}
+
+// Compressor is a feed-forward compressor with a soft knee
+type Compressor struct {
+	sidechain
+
+	// Threshold is the level in dB above which the signal is compressed
+	Threshold float64
+	// Ratio is the input to output ratio of level changes above the threshold
+	Ratio float64
+	// Knee is the width in dB of the soft transition around the threshold
+	Knee float64
+	// Attack is the time in milliseconds the compressor takes to react to a level increase
+	Attack float64
+	// Release is the time in milliseconds the compressor takes to recover after a level decrease
+	Release float64
+	// Makeup is the gain in dB applied after compression
+	Makeup    float64
+	Detection Detection
+
+	sampleRate float64
+	gr         [volume.MaxChannels]float64
+	p          params
+}
+
+// NewCompressor returns a compressor for the sample rate provided
+func NewCompressor(sampleRate float64) *Compressor {
+	c := &Compressor{
+		Threshold:  -18,
+		Ratio:      4,
+		Knee:       6,
+		Attack:     10,
+		Release:    100,
+		sampleRate: sampleRate,
+	}
+	c.p = params{effect: "compressor"}
+	c.p.add("threshold", -60, 0, -18, &c.Threshold)
+	c.p.add("ratio", 1, 100, 4, &c.Ratio)
+	c.p.add("knee", 0, 24, 6, &c.Knee)
+	c.p.add("attack", 0, 500, 10, &c.Attack)
+	c.p.add("release", 1, 5000, 100, &c.Release)
+	c.p.add("makeup", 0, 48, 0, &c.Makeup)
+	return c
+}
+
+// gainComputer returns the gain change in dB for an input level in dB
+func (c *Compressor) gainComputer(level float64) float64 {
+	ratio := math.Max(c.Ratio, 1)
+	over := level - c.Threshold
+	switch {
+	case 2*over < -c.Knee:
+		return 0
+	case c.Knee > 0 && 2*math.Abs(over) <= c.Knee:
+		x := over + c.Knee/2
+		return (1/ratio - 1) * x * x / (2 * c.Knee)
+	default:
+		return over/ratio - over
+	}
+}
+
+// Process applies the compressor to the mix buffer in place
+func (c *Compressor) Process(buf mixing.MixBuffer) {
+	attack := volume.SmoothingCoeff(c.Attack, c.sampleRate)
+	release := volume.SmoothingCoeff(c.Release, c.sampleRate)
+	for i := range buf {
+		samp := &buf[i]
+		levels := detect(c.keyLevels(buf, i), samp.Channels, c.Detection)
+		for ch := 0; ch < samp.Channels; ch++ {
+			target := c.gainComputer(math.Max(volume.ToDB(levels[ch]), minLevelDB))
+			coeff := release
+			if target < c.gr[ch] {
+				coeff = attack
+			}
+			c.gr[ch] = target + (c.gr[ch]-target)*coeff
+			samp.StaticMatrix[ch] = volume.Volume(float64(samp.StaticMatrix[ch]) * volume.FromDB(c.gr[ch]+c.Makeup))
+		}
+	}
+	c.key = nil
+}
+
+// GainReduction returns the current largest gain reduction in dB, as a negative number
+func (c *Compressor) GainReduction() float64 {
+	gr := 0.0
+	for _, g := range c.gr {
+		gr = math.Min(gr, g)
+	}
+	return gr
+}
+
+// Reset clears the compressor's gain state
+func (c *Compressor) Reset() {
+	for ch := range c.gr {
+		c.gr[ch] = 0
+	}
+	c.key = nil
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (c *Compressor) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the compressor
+func (c *Compressor) Parameters() []mixing.ParameterInfo {
+	return c.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (c *Compressor) GetParameter(name string) (float64, error) {
+	return c.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (c *Compressor) SetParameter(name string, value float64) error {
+	return c.p.set(name, value)
+}
+
+// Limiter is a look-ahead brickwall limiter. The signal is delayed by the look-ahead time so
+// that gain reduction ramps in smoothly and is fully applied by the time a peak arrives,
+// keeping the output at or below the ceiling.
+type Limiter struct {
+	sidechain
+
+	// Ceiling is the maximum output level in dB
+	Ceiling float64
+	// Release is the time in milliseconds the limiter takes to recover after a peak
+	Release   float64
+	Detection Detection
+
+	sampleRate float64
+	window     int
+	channels   [volume.MaxChannels]limiterChannel
+	p          params
+}
+
+type limiterChannel struct {
+	hold  slidingMin
+	box   []float64
+	boxAt int
+	sum   float64
+	delay delayLine
+	gain  float64
+}
+
+// NewLimiter returns a limiter for the sample rate provided with `lookahead` milliseconds of look-ahead
+func NewLimiter(sampleRate float64, lookahead float64) *Limiter {
+	window := int(math.Round(lookahead * sampleRate / 1000))
+	if window < 1 {
+		window = 1
+	}
+	l := &Limiter{
+		Ceiling:    -0.3,
+		Release:    50,
+		sampleRate: sampleRate,
+		window:     window,
+	}
+	for ch := range l.channels {
+		lc := &l.channels[ch]
+		lc.hold = newSlidingMin(window)
+		lc.box = make([]float64, window)
+		lc.delay = newDelayLine(window + 1)
+	}
+	l.Reset()
+
+	l.p = params{effect: "limiter"}
+	l.p.add("ceiling", -24, 0, -0.3, &l.Ceiling)
+	l.p.add("release", 1, 5000, 50, &l.Release)
+	return l
+}
+
+// Process applies the limiter to the mix buffer in place
+func (l *Limiter) Process(buf mixing.MixBuffer) {
+	ceiling := volume.FromDB(l.Ceiling)
+	release := 1 - volume.SmoothingCoeff(l.Release, l.sampleRate)
+	for i := range buf {
+		samp := &buf[i]
+		levels := detect(l.keyLevels(buf, i), samp.Channels, l.Detection)
+		for ch := 0; ch < samp.Channels; ch++ {
+			lc := &l.channels[ch]
+			need := 1.0
+			if levels[ch] > ceiling {
+				need = ceiling / levels[ch]
+			}
+
+			// hold the lowest gain needed over the look-ahead window, then smooth it with a
+			// moving average of the same length so it reaches the held value right as the peak arrives
+			held := lc.hold.push(need)
+			lc.sum += held - lc.box[lc.boxAt]
+			lc.box[lc.boxAt] = held
+			lc.boxAt++
+			if lc.boxAt >= len(lc.box) {
+				lc.boxAt = 0
+			}
+			smoothed := lc.sum / float64(len(lc.box))
+
+			lc.gain += (1 - lc.gain) * release
+			if smoothed < lc.gain {
+				lc.gain = smoothed
+			}
+
+			x := lc.delay.process(float64(samp.StaticMatrix[ch]), l.window-1)
+			samp.StaticMatrix[ch] = volume.Volume(x * lc.gain)
+		}
+	}
+	l.key = nil
+}
+
+// GainReduction returns the current largest gain reduction in dB, as a negative number
+func (l *Limiter) GainReduction() float64 {
+	gain := 1.0
+	for _, lc := range l.channels {
+		gain = math.Min(gain, lc.gain)
+	}
+	return math.Max(volume.ToDB(gain), minLevelDB)
+}
+
+// Reset clears the limiter's delay line and gain state
+func (l *Limiter) Reset() {
+	for ch := range l.channels {
+		lc := &l.channels[ch]
+		lc.hold.reset()
+		for i := range lc.box {
+			lc.box[i] = 1
+		}
+		lc.boxAt = 0
+		lc.sum = float64(len(lc.box))
+		lc.delay.reset()
+		lc.gain = 1
+	}
+	l.key = nil
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (l *Limiter) Latency() int {
+	return l.window - 1
+}
+
+// Parameters returns the descriptions of the parameters of the limiter
+func (l *Limiter) Parameters() []mixing.ParameterInfo {
+	return l.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (l *Limiter) GetParameter(name string) (float64, error) {
+	return l.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (l *Limiter) SetParameter(name string, value float64) error {
+	return l.p.set(name, value)
+}
+
+// Gate is a downward expander that attenuates the signal below a threshold.
+// With a high ratio it acts as a noise gate.
+type Gate struct {
+	sidechain
+
+	// Threshold is the level in dB below which the signal is attenuated
+	Threshold float64
+	// Ratio is the expansion ratio below the threshold
+	Ratio float64
+	// Range is the maximum attenuation in dB, as a negative number
+	Range float64
+	// Attack is the time in milliseconds the gate takes to open
+	Attack float64
+	// Hold is the time in milliseconds the gate stays open after the level drops below the threshold
+	Hold float64
+	// Release is the time in milliseconds the gate takes to close
+	Release   float64
+	Detection Detection
+
+	sampleRate float64
+	gr         [volume.MaxChannels]float64
+	holdLeft   [volume.MaxChannels]int
+	p          params
+}
+
+// NewGate returns a noise gate for the sample rate provided
+func NewGate(sampleRate float64) *Gate {
+	g := &Gate{
+		Threshold:  -50,
+		Ratio:      20,
+		Range:      -80,
+		Attack:     1,
+		Hold:       50,
+		Release:    100,
+		sampleRate: sampleRate,
+	}
+	g.p = params{effect: "gate"}
+	g.p.add("threshold", -100, 0, -50, &g.Threshold)
+	g.p.add("ratio", 1, 100, 20, &g.Ratio)
+	g.p.add("range", -120, 0, -80, &g.Range)
+	g.p.add("attack", 0, 500, 1, &g.Attack)
+	g.p.add("hold", 0, 5000, 50, &g.Hold)
+	g.p.add("release", 1, 5000, 100, &g.Release)
+	return g
+}
+
+// Process applies the gate to the mix buffer in place
+func (g *Gate) Process(buf mixing.MixBuffer) {
+	attack := volume.SmoothingCoeff(g.Attack, g.sampleRate)
+	release := volume.SmoothingCoeff(g.Release, g.sampleRate)
+	hold := int(g.Hold * g.sampleRate / 1000)
+	for i := range buf {
+		samp := &buf[i]
+		levels := detect(g.keyLevels(buf, i), samp.Channels, g.Detection)
+		for ch := 0; ch < samp.Channels; ch++ {
+			level := math.Max(volume.ToDB(levels[ch]), minLevelDB)
+			target := 0.0
+			if level < g.Threshold {
+				target = math.Max((level-g.Threshold)*(math.Max(g.Ratio, 1)-1), g.Range)
+			} else {
+				g.holdLeft[ch] = hold
+			}
+
+			switch {
+			case target > g.gr[ch]:
+				g.gr[ch] = target + (g.gr[ch]-target)*attack
+			case level >= g.Threshold:
+				// fully open; the hold time starts counting once the level drops
+			case g.holdLeft[ch] > 0:
+				g.holdLeft[ch]--
+			default:
+				g.gr[ch] = target + (g.gr[ch]-target)*release
+			}
+			samp.StaticMatrix[ch] = volume.Volume(float64(samp.StaticMatrix[ch]) * volume.FromDB(g.gr[ch]))
+		}
+	}
+	g.key = nil
+}
+
+// GainReduction returns the current largest gain reduction in dB, as a negative number
+func (g *Gate) GainReduction() float64 {
+	gr := 0.0
+	for _, v := range g.gr {
+		gr = math.Min(gr, v)
+	}
+	return gr
+}
+
+// Reset clears the gate's gain state
+func (g *Gate) Reset() {
+	for ch := range g.gr {
+		g.gr[ch] = 0
+		g.holdLeft[ch] = 0
+	}
+	g.key = nil
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (g *Gate) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the gate
+func (g *Gate) Parameters() []mixing.ParameterInfo {
+	return g.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (g *Gate) GetParameter(name string) (float64, error) {
+	return g.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (g *Gate) SetParameter(name string, value float64) error {
+	return g.p.set(name, value)
+}
+
+// slidingMin tracks the minimum of the last `window` values pushed into it
+type slidingMin struct {
+	vals   []float64
+	idxs   []int64
+	head   int
+	size   int
+	n      int64
+	window int64
+}
+
+func newSlidingMin(window int) slidingMin {
+	return slidingMin{
+		vals:   make([]float64, window+1),
+		idxs:   make([]int64, window+1),
+		window: int64(window),
+	}
+}
+
+// push adds a value and returns the minimum of the current window
+func (m *slidingMin) push(v float64) float64 {
+	capacity := len(m.vals)
+	for m.size > 0 && m.vals[(m.head+m.size-1)%capacity] >= v {
+		m.size--
+	}
+	back := (m.head + m.size) % capacity
+	m.vals[back] = v
+	m.idxs[back] = m.n
+	m.size++
+	for m.idxs[m.head] <= m.n-m.window {
+		m.head = (m.head + 1) % capacity
+		m.size--
+	}
+	m.n++
+	return m.vals[m.head]
+}
+
+func (m *slidingMin) reset() {
+	m.head = 0
+	m.size = 0
+	m.n = 0
+}
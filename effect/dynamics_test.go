@@ -0,0 +1,222 @@
+package effect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// stereoConst returns a stereo mix buffer with the same value in every sample
+func stereoConst(v float64, n int) mixing.MixBuffer {
+	buf := make(mixing.MixBuffer, n)
+	for i := range buf {
+		buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{volume.Volume(v), volume.Volume(v)}, Channels: 2}
+	}
+	return buf
+}
+
+func TestLimiterNeverExceedsCeiling(t *testing.T) {
+	const sampleRate = 48000
+	for _, lookahead := range []float64{0.1, 1, 5} {
+		l := NewLimiter(sampleRate, lookahead)
+		l.Ceiling = -1
+		ceiling := volume.FromDB(l.Ceiling)
+
+		// quiet, then a step well over the ceiling, then an isolated spike
+		buf := stereoConst(0.1, sampleRate/2)
+		for i := 1000; i < 5000; i++ {
+			buf[i].StaticMatrix[0], buf[i].StaticMatrix[1] = 2, -2
+		}
+		buf[10000].StaticMatrix[0] = 4
+		l.Process(buf)
+		for i, s := range buf {
+			for c := 0; c < 2; c++ {
+				if v := math.Abs(float64(s.StaticMatrix[c])); v > ceiling+1e-9 {
+					t.Fatalf("lookahead %vms: sample %d channel %d is %v, over the ceiling %v", lookahead, i, c, v, ceiling)
+				}
+			}
+		}
+	}
+}
+
+func TestLimiterLatencyMatchesDelay(t *testing.T) {
+	for _, lookahead := range []float64{0.1, 1, 5} {
+		l := NewLimiter(48000, lookahead)
+		buf := stereoImpulse(1000)
+		buf[0].StaticMatrix[0], buf[0].StaticMatrix[1] = 0.5, 0.5
+		l.Process(buf)
+		if got, want := onset(buf), l.Latency(); got != want {
+			t.Errorf("lookahead %vms: impulse arrived at %d, want %d", lookahead, got, want)
+		}
+		if got := buf[l.Latency()].StaticMatrix[0]; got != 0.5 {
+			t.Errorf("lookahead %vms: impulse below the ceiling changed to %v", lookahead, got)
+		}
+	}
+}
+
+func TestCompressorSteadyStateGain(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold float64
+		ratio     float64
+		knee      float64
+		makeup    float64
+		levelDB   float64
+		wantDB    float64
+	}{
+		{"below threshold", -18, 4, 0, 0, -24, -24},
+		{"hard knee", -18, 4, 0, 0, -6, -18 + 12.0/4},
+		{"makeup", -18, 4, 0, 6, -6, -18 + 12.0/4 + 6},
+		{"limiting ratio", -20, 100, 0, 0, 0, -20 + 20.0/100},
+		{"soft knee below", -18, 4, 6, 0, -21.5, -21.5},
+		{"soft knee at threshold", -18, 4, 6, 0, -18, -18 + (1.0/4-1)*9/12},
+		{"soft knee above", -18, 4, 6, 0, -6, -18 + 12.0/4},
+	}
+	for _, tt := range tests {
+		c := NewCompressor(48000)
+		c.Threshold = tt.threshold
+		c.Ratio = tt.ratio
+		c.Knee = tt.knee
+		c.Makeup = tt.makeup
+		c.Attack = 1
+		c.Release = 1
+
+		buf := stereoConst(volume.FromDB(tt.levelDB), 48000)
+		c.Process(buf)
+		for ch := 0; ch < 2; ch++ {
+			got := volume.ToDB(float64(buf[len(buf)-1].StaticMatrix[ch]))
+			if math.Abs(got-tt.wantDB) > 1e-6 {
+				t.Errorf("%s: channel %d got %.4f dB, want %.4f dB", tt.name, ch, got, tt.wantDB)
+			}
+		}
+	}
+}
+
+func TestCompressorAttackRelease(t *testing.T) {
+	const sampleRate = 48000
+	c := NewCompressor(sampleRate)
+	c.Knee = 0
+	c.Attack = 10
+	buf := stereoConst(1, sampleRate/100)
+	c.Process(buf)
+	// after one attack time constant, 1-1/e of the -13.5 dB reduction has been applied
+	want := -13.5 * (1 - math.Exp(-1))
+	if got := c.GainReduction(); math.Abs(got-want) > 0.01 {
+		t.Errorf("gain reduction after the attack time: got %v, want %v", got, want)
+	}
+	c.Reset()
+	if got := c.GainReduction(); got != 0 {
+		t.Errorf("gain reduction after reset: got %v, want 0", got)
+	}
+}
+
+func TestGateClosesAndHolds(t *testing.T) {
+	const sampleRate = 48000
+	g := NewGate(sampleRate)
+	g.Threshold = -40
+	g.Ratio = 20
+	g.Range = -60
+	g.Attack = 0
+	g.Hold = 10
+	g.Release = 5
+	holdSamples := sampleRate * 10 / 1000
+
+	// loud enough to open the gate, then quiet
+	g.Process(stereoConst(0.5, 1000))
+	if got := g.GainReduction(); got != 0 {
+		t.Fatalf("open gate: got %v dB, want 0", got)
+	}
+	quiet := volume.FromDB(-50)
+	g.Process(stereoConst(quiet, holdSamples))
+	if got := g.GainReduction(); got != 0 {
+		t.Errorf("gate during hold: got %v dB, want 0", got)
+	}
+	g.Process(stereoConst(quiet, sampleRate*5/1000))
+	// (-50 - -40) * (20-1) is below the range, so the gate releases toward -60 dB
+	if got, want := g.GainReduction(), -60*(1-math.Exp(-1)); math.Abs(got-want) > 0.5 {
+		t.Errorf("gate after one release time constant: got %v dB, want %v dB", got, want)
+	}
+	g.Process(stereoConst(quiet, sampleRate))
+	if got := g.GainReduction(); math.Abs(got+60) > 1e-6 {
+		t.Errorf("closed gate: got %v dB, want -60 dB", got)
+	}
+
+	// the expansion ratio applies until the range is reached
+	g.Range = -80
+	buf := stereoConst(volume.FromDB(-41), sampleRate)
+	g.Process(buf)
+	if got, want := volume.ToDB(float64(buf[len(buf)-1].StaticMatrix[0])), -41.0-19; math.Abs(got-want) > 1e-4 {
+		t.Errorf("expanded level: got %v dB, want %v dB", got, want)
+	}
+
+	// with attack at zero, the gate reopens on the first loud sample
+	buf = stereoConst(0.5, 10)
+	g.Process(buf)
+	if got := buf[0].StaticMatrix[0]; got != 0.5 {
+		t.Errorf("reopened gate: got %v, want 0.5", got)
+	}
+}
+
+func TestSidechainDrivesGainReduction(t *testing.T) {
+	const sampleRate = 48000
+	main := volume.FromDB(-40)
+
+	c := NewCompressor(sampleRate)
+	c.Knee = 0
+	c.Attack = 0
+	buf := stereoConst(main, 1000)
+	c.Process(buf)
+	if got := c.GainReduction(); got != 0 {
+		t.Errorf("compressor without a key: got %v dB, want 0", got)
+	}
+
+	// a full-scale mono key compresses the quiet main signal as if it were the input
+	key := make(mixing.MixBuffer, 1000)
+	for i := range key {
+		key[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{1}, Channels: 1}
+	}
+	c.SetSidechain(key)
+	buf = stereoConst(main, 1000)
+	c.Process(buf)
+	if got, want := c.GainReduction(), -18.0*3/4; math.Abs(got-want) > 1e-6 {
+		t.Errorf("compressor with a loud key: got %v dB, want %v dB", got, want)
+	}
+	if got, want := volume.ToDB(float64(buf[999].StaticMatrix[1])), -40-18.0*3/4; math.Abs(got-want) > 1e-6 {
+		t.Errorf("keyed output: got %v dB, want %v dB", got, want)
+	}
+
+	// the key only applies to one call of Process
+	c.Release = 0
+	c.Process(stereoConst(main, 10))
+	if got := c.GainReduction(); got != 0 {
+		t.Errorf("compressor after the key was used: got %v dB, want 0", got)
+	}
+
+	// a silent key closes the gate even though the main signal is loud
+	g := NewGate(sampleRate)
+	g.Attack = 0
+	g.Release = 0
+	g.Hold = 0
+	silent := make(mixing.MixBuffer, 100)
+	g.SetSidechain(silent)
+	buf = stereoConst(0.5, 100)
+	g.Process(buf)
+	if got := g.GainReduction(); got != g.Range {
+		t.Errorf("gate with a silent key: got %v dB, want %v dB", got, g.Range)
+	}
+
+	// a loud key makes the limiter pull down a signal that is already under the ceiling
+	l := NewLimiter(sampleRate, 1)
+	l.Ceiling = -6
+	for i := range key {
+		key[i].StaticMatrix[0] = 1
+	}
+	l.SetSidechain(key)
+	buf = stereoConst(0.25, 1000)
+	l.Process(buf)
+	if got, want := float64(buf[999].StaticMatrix[0]), 0.25*volume.FromDB(-6); math.Abs(got-want) > 1e-6 {
+		t.Errorf("keyed limiter output: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,62 @@
+package effect
+
+import (
+	"fmt"
+
+	"github.com/gotracker/gomixing/filter"
+	"github.com/gotracker/gomixing/mixing"
+)
+
+// DefaultEQSmoothing is the default time in samples over which EQ parameter changes are smoothed
+const DefaultEQSmoothing = 64
+
+// EQ is a multi-band parametric equalizer effect
+type EQ struct {
+	filter.EQ
+}
+
+// NewEQ returns an equalizer effect with no bands
+func NewEQ(sampleRate float64) *EQ {
+	return &EQ{
+		EQ: *filter.NewEQ(sampleRate, DefaultEQSmoothing),
+	}
+}
+
+// Process applies the equalizer to the mix buffer in place
+func (e *EQ) Process(buf mixing.MixBuffer) {
+	for i := range buf {
+		buf[i] = e.Filter(buf[i])
+	}
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (e *EQ) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of every band.
+// Band parameters are named `band<N>.frequency`, `band<N>.q` and `band<N>.gain`
+func (e *EQ) Parameters() []mixing.ParameterInfo {
+	return e.params().infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (e *EQ) GetParameter(name string) (float64, error) {
+	return e.params().get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (e *EQ) SetParameter(name string, value float64) error {
+	return e.params().set(name, value)
+}
+
+func (e *EQ) params() params {
+	p := params{effect: "eq"}
+	nyquist := e.SampleRate / 2
+	for i, b := range e.Bands {
+		p.add(fmt.Sprintf("band%d.frequency", i), 10, nyquist, 1000, &b.Frequency)
+		p.add(fmt.Sprintf("band%d.q", i), 0.1, 24, 0.7071, &b.Q)
+		p.add(fmt.Sprintf("band%d.gain", i), -24, 24, 0, &b.Gain)
+	}
+	return p
+}
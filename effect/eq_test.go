@@ -0,0 +1,63 @@
+package effect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/filter"
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// stereoSine returns a stereo mix buffer holding a sine at `freq` Hz in both channels
+func stereoSine(sampleRate float64, freq float64, amplitude float64, n int) mixing.MixBuffer {
+	buf := make(mixing.MixBuffer, n)
+	for i := range buf {
+		v := volume.Volume(amplitude * math.Sin(2*math.Pi*freq*float64(i)/sampleRate))
+		buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{v, v}, Channels: 2}
+	}
+	return buf
+}
+
+// peak returns the largest absolute value of a channel from `from` onwards
+func peak(buf mixing.MixBuffer, channel int, from int) float64 {
+	var p float64
+	for _, s := range buf[from:] {
+		p = math.Max(p, math.Abs(float64(s.StaticMatrix[channel])))
+	}
+	return p
+}
+
+func TestEQEffectGainAtCenter(t *testing.T) {
+	const sampleRate = 48000
+	e := NewEQ(sampleRate)
+	e.AddBand(filter.BandPeaking, 1000, 1, 6)
+
+	buf := stereoSine(sampleRate, 1000, 0.25, sampleRate/2)
+	e.Process(buf)
+	for c := 0; c < 2; c++ {
+		if got := 20 * math.Log10(peak(buf, c, len(buf)/2)/0.25); math.Abs(got-6) > 0.05 {
+			t.Errorf("channel %d: got %.3f dB, want 6 dB", c, got)
+		}
+	}
+}
+
+func TestEQEffectParameters(t *testing.T) {
+	e := NewEQ(48000)
+	e.AddBand(filter.BandPeaking, 1000, 1, 0)
+	if err := e.SetParameter("band0.gain", 40); err != nil {
+		t.Fatalf("SetParameter: %v", err)
+	}
+	if got, _ := e.GetParameter("band0.gain"); got != 24 {
+		t.Errorf("gain set above range: got %v, want 24", got)
+	}
+	if err := e.SetParameter("band0.frequency", 2000); err != nil {
+		t.Fatalf("SetParameter: %v", err)
+	}
+	if got := e.Response(2000); math.Abs(got-24) > 0.01 {
+		t.Errorf("response at the new center: got %.3f dB, want 24 dB", got)
+	}
+	if got := len(e.Parameters()); got != 3 {
+		t.Errorf("parameters of a single band: got %d, want 3", got)
+	}
+}
@@ -0,0 +1,225 @@
+package effect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/gotracker/gomixing/sampling"
+)
+
+var (
+	// ErrInvalidWave is returned when impulse response data is not a valid RIFF WAVE file
+	ErrInvalidWave = errors.New("invalid wave file")
+	// ErrUnsupportedWave is returned when impulse response data is in a wave format that can't be decoded
+	ErrUnsupportedWave = errors.New("unsupported wave format")
+)
+
+// MaxImpulseResponseSize is the largest wave data chunk, in bytes, that will be loaded as an impulse response
+const MaxImpulseResponseSize = 64 << 20
+
+const (
+	// maxWaveFormatSize is the largest format chunk accepted; real ones are at most 40 bytes
+	maxWaveFormatSize = 1024
+
+	waveFormatPCM        = 1
+	waveFormatIEEEFloat  = 3
+	waveFormatExtensible = 0xFFFE
+)
+
+// ImpulseResponse is the recorded response of a space, one slice of samples per channel
+type ImpulseResponse struct {
+	SampleRate float64
+	Channels   [][]float64
+}
+
+// Len returns the length of the impulse response in samples
+func (ir *ImpulseResponse) Len() int {
+	n := 0
+	for _, ch := range ir.Channels {
+		if len(ch) > n {
+			n = len(ch)
+		}
+	}
+	return n
+}
+
+// Normalize scales the impulse response so that its loudest channel has unit energy
+func (ir *ImpulseResponse) Normalize() {
+	var energy float64
+	for _, ch := range ir.Channels {
+		var e float64
+		for _, v := range ch {
+			e += v * v
+		}
+		if e > energy {
+			energy = e
+		}
+	}
+	if energy == 0 {
+		return
+	}
+	scale := 1 / math.Sqrt(energy)
+	for _, ch := range ir.Channels {
+		for i := range ch {
+			ch[i] *= scale
+		}
+	}
+}
+
+// LoadImpulseResponseFile loads an impulse response from a wave file on disk
+func LoadImpulseResponseFile(filename string) (*ImpulseResponse, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadImpulseResponse(f)
+}
+
+// LoadImpulseResponse loads an impulse response from RIFF WAVE data.
+// Integer PCM of 8, 16, 24 and 32 bits and 32- and 64-bit floating-point data are supported
+func LoadImpulseResponse(r io.Reader) (*ImpulseResponse, error) {
+	var riff struct {
+		ID   [4]byte
+		Size uint32
+		Type [4]byte
+	}
+	if err := binary.Read(r, binary.LittleEndian, &riff); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWave, err)
+	}
+	if string(riff.ID[:]) != "RIFF" || string(riff.Type[:]) != "WAVE" {
+		return nil, ErrInvalidWave
+	}
+
+	var format struct {
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+	}
+	haveFormat := false
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(r, binary.LittleEndian, &chunk); err != nil {
+			return nil, fmt.Errorf("%w: missing data chunk", ErrInvalidWave)
+		}
+		size := int64(chunk.Size)
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			if size > maxWaveFormatSize {
+				return nil, fmt.Errorf("%w: format chunk of %d bytes", ErrInvalidWave, size)
+			}
+			body, err := readWaveChunk(r, size)
+			if err != nil {
+				return nil, err
+			}
+			if err := binary.Read(bytes.NewReader(body), binary.LittleEndian, &format); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidWave, err)
+			}
+			if format.AudioFormat == waveFormatExtensible && len(body) >= 26 {
+				// the sub-format GUID starts with the real format code
+				format.AudioFormat = binary.LittleEndian.Uint16(body[24:])
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("%w: data before format", ErrInvalidWave)
+			}
+			if size > MaxImpulseResponseSize {
+				return nil, fmt.Errorf("%w: data chunk of %d bytes", ErrUnsupportedWave, size)
+			}
+			body, err := readWaveChunk(r, size)
+			if err != nil {
+				return nil, err
+			}
+			return decodeWaveData(body, int(format.AudioFormat), int(format.Channels), int(format.BitsPerSample), float64(format.SampleRate))
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size&1); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidWave, err)
+			}
+		}
+	}
+}
+
+// readWaveChunk reads the body of a chunk and skips the padding byte that follows odd-sized chunks.
+// The body is read incrementally, so a truncated file can't cause a large allocation
+func readWaveChunk(r io.Reader, size int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWave, err)
+	}
+	if int64(len(body)) != size {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWave, io.ErrUnexpectedEOF)
+	}
+	if size&1 != 0 {
+		// a missing padding byte at the end of the file is harmless
+		_, _ = io.CopyN(io.Discard, r, 1)
+	}
+	return body, nil
+}
+
+func decodeWaveData(data []byte, audioFormat int, channels int, bitsPerSample int, sampleRate float64) (*ImpulseResponse, error) {
+	if channels <= 0 {
+		return nil, fmt.Errorf("%w: no channels", ErrInvalidWave)
+	}
+
+	var read func(data []byte, ofs int) float64
+	bytesPerSample := (bitsPerSample + 7) / 8
+	switch {
+	case audioFormat == waveFormatPCM && bitsPerSample == 24:
+		read = func(data []byte, ofs int) float64 {
+			v := int32(uint32(data[ofs])<<8|uint32(data[ofs+1])<<16|uint32(data[ofs+2])<<24) >> 8
+			return float64(v) / 8388608.0
+		}
+	case audioFormat == waveFormatPCM && bitsPerSample == 32:
+		read = func(data []byte, ofs int) float64 {
+			return float64(int32(binary.LittleEndian.Uint32(data[ofs:]))) / 2147483648.0
+		}
+	default:
+		var sampleFormat sampling.Format
+		switch {
+		case audioFormat == waveFormatPCM && bitsPerSample == 8:
+			sampleFormat = sampling.Format8BitUnsigned
+		case audioFormat == waveFormatPCM && bitsPerSample == 16:
+			sampleFormat = sampling.Format16BitLESigned
+		case audioFormat == waveFormatIEEEFloat && bitsPerSample == 32:
+			sampleFormat = sampling.Format32BitLEFloat
+		case audioFormat == waveFormatIEEEFloat && bitsPerSample == 64:
+			sampleFormat = sampling.Format64BitLEFloat
+		default:
+			return nil, fmt.Errorf("%w: format %d with %d bits per sample", ErrUnsupportedWave, audioFormat, bitsPerSample)
+		}
+		formatter := sampling.GetFormatter(sampleFormat)
+		read = func(data []byte, ofs int) float64 {
+			v, _ := formatter.ReadAt(data, int64(ofs))
+			return float64(v)
+		}
+	}
+
+	frameSize := bytesPerSample * channels
+	frames := len(data) / frameSize
+	ir := &ImpulseResponse{
+		SampleRate: sampleRate,
+		Channels:   make([][]float64, channels),
+	}
+	for c := range ir.Channels {
+		ir.Channels[c] = make([]float64, frames)
+	}
+	for i := 0; i < frames; i++ {
+		for c := 0; c < channels; c++ {
+			ir.Channels[c][i] = read(data, i*frameSize+c*bytesPerSample)
+		}
+	}
+	return ir, nil
+}
@@ -0,0 +1,190 @@
+package effect
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// waveSamples are exactly representable in every supported wave format
+var waveSamples = [][]float64{
+	{0, 0.5, -0.5, -1, 0.25, -0.25},
+	{0.125, -0.125, 0.75, -0.75, 0, 0.5},
+}
+
+type waveChunk struct {
+	id   string
+	body []byte
+}
+
+func buildWave(chunks ...waveChunk) []byte {
+	var body bytes.Buffer
+	body.WriteString("WAVE")
+	for _, c := range chunks {
+		body.WriteString(c.id)
+		_ = binary.Write(&body, binary.LittleEndian, uint32(len(c.body)))
+		body.Write(c.body)
+		if len(c.body)&1 != 0 {
+			body.WriteByte(0)
+		}
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	_ = binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func waveFormatChunk(audioFormat int, channels int, bits int, extensible bool) waveChunk {
+	var b bytes.Buffer
+	blockAlign := channels * bits / 8
+	tag := audioFormat
+	if extensible {
+		tag = waveFormatExtensible
+	}
+	_ = binary.Write(&b, binary.LittleEndian, []uint16{uint16(tag), uint16(channels)})
+	_ = binary.Write(&b, binary.LittleEndian, []uint32{48000, uint32(48000 * blockAlign)})
+	_ = binary.Write(&b, binary.LittleEndian, []uint16{uint16(blockAlign), uint16(bits)})
+	if extensible {
+		_ = binary.Write(&b, binary.LittleEndian, []uint16{22, uint16(bits)})
+		_ = binary.Write(&b, binary.LittleEndian, uint32(3))
+		// KSDATAFORMAT_SUBTYPE_* GUID: the format code followed by the fixed suffix
+		_ = binary.Write(&b, binary.LittleEndian, uint16(audioFormat))
+		b.Write([]byte{0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xAA, 0x00, 0x38, 0x9B, 0x71})
+	}
+	return waveChunk{id: "fmt ", body: b.Bytes()}
+}
+
+func waveDataChunk(audioFormat int, bits int, samples [][]float64) waveChunk {
+	var b bytes.Buffer
+	for i := range samples[0] {
+		for _, ch := range samples {
+			v := ch[i]
+			switch {
+			case audioFormat == waveFormatIEEEFloat && bits == 32:
+				_ = binary.Write(&b, binary.LittleEndian, float32(v))
+			case audioFormat == waveFormatIEEEFloat && bits == 64:
+				_ = binary.Write(&b, binary.LittleEndian, v)
+			case bits == 8:
+				b.WriteByte(uint8(int(v*128) + 128))
+			case bits == 16:
+				_ = binary.Write(&b, binary.LittleEndian, int16(v*32768))
+			case bits == 24:
+				s := int32(v * 8388608)
+				b.Write([]byte{byte(s), byte(s >> 8), byte(s >> 16)})
+			case bits == 32:
+				_ = binary.Write(&b, binary.LittleEndian, int32(v*2147483648))
+			}
+		}
+	}
+	return waveChunk{id: "data", body: b.Bytes()}
+}
+
+func TestLoadImpulseResponseFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		audioFormat int
+		bits        int
+	}{
+		{name: "pcm8", audioFormat: waveFormatPCM, bits: 8},
+		{name: "pcm16", audioFormat: waveFormatPCM, bits: 16},
+		{name: "pcm24", audioFormat: waveFormatPCM, bits: 24},
+		{name: "pcm32", audioFormat: waveFormatPCM, bits: 32},
+		{name: "float32", audioFormat: waveFormatIEEEFloat, bits: 32},
+		{name: "float64", audioFormat: waveFormatIEEEFloat, bits: 64},
+	}
+
+	for _, tt := range tests {
+		for _, extensible := range []bool{false, true} {
+			name := tt.name
+			if extensible {
+				name += " extensible"
+			}
+			t.Run(name, func(t *testing.T) {
+				data := buildWave(
+					waveFormatChunk(tt.audioFormat, len(waveSamples), tt.bits, extensible),
+					waveDataChunk(tt.audioFormat, tt.bits, waveSamples),
+				)
+				ir, err := LoadImpulseResponse(bytes.NewReader(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if ir.SampleRate != 48000 {
+					t.Errorf("sample rate: got %v, want 48000", ir.SampleRate)
+				}
+				if len(ir.Channels) != len(waveSamples) {
+					t.Fatalf("channels: got %d, want %d", len(ir.Channels), len(waveSamples))
+				}
+				for c, want := range waveSamples {
+					if len(ir.Channels[c]) != len(want) {
+						t.Fatalf("channel %d: got %d samples, want %d", c, len(ir.Channels[c]), len(want))
+					}
+					for i, w := range want {
+						if got := ir.Channels[c][i]; math.Abs(got-w) > 1e-9 {
+							t.Errorf("channel %d sample %d: got %v, want %v", c, i, got, w)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLoadImpulseResponseSkipsChunks(t *testing.T) {
+	mono := waveSamples[:1]
+	data := buildWave(
+		waveChunk{id: "JUNK", body: []byte{1, 2, 3}},
+		waveFormatChunk(waveFormatPCM, 1, 16, false),
+		waveChunk{id: "LIST", body: make([]byte, 4097)},
+		waveDataChunk(waveFormatPCM, 16, mono),
+	)
+	ir, err := LoadImpulseResponse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := ir.Len(); got != len(mono[0]) {
+		t.Errorf("length: got %d, want %d", got, len(mono[0]))
+	}
+}
+
+func TestLoadImpulseResponseInvalid(t *testing.T) {
+	format := waveFormatChunk(waveFormatPCM, 1, 16, false)
+	data := waveDataChunk(waveFormatPCM, 16, waveSamples[:1])
+
+	truncated := buildWave(format, data)
+	truncated = truncated[:len(truncated)-3]
+
+	huge := buildWave(format)
+	huge = append(huge, []byte("data\xff\xff\xff\xff")...)
+
+	hugeUnknown := buildWave(waveChunk{id: "JUNK"})
+	binary.LittleEndian.PutUint32(hugeUnknown[len(hugeUnknown)-4:], 0xFFFFFFFF)
+
+	tests := []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{name: "empty", data: nil, want: ErrInvalidWave},
+		{name: "not riff", data: []byte("RIFX\x00\x00\x00\x00WAVE"), want: ErrInvalidWave},
+		{name: "no data", data: buildWave(format), want: ErrInvalidWave},
+		{name: "data before format", data: buildWave(data, format), want: ErrInvalidWave},
+		{name: "truncated data", data: truncated, want: ErrInvalidWave},
+		{name: "huge data chunk", data: huge, want: ErrUnsupportedWave},
+		{name: "huge unknown chunk", data: hugeUnknown, want: ErrInvalidWave},
+		{name: "huge format chunk", data: buildWave(waveChunk{id: "fmt ", body: make([]byte, maxWaveFormatSize+1)}), want: ErrInvalidWave},
+		{name: "unsupported bits", data: buildWave(waveFormatChunk(waveFormatPCM, 1, 12, false), data), want: ErrUnsupportedWave},
+		{name: "no channels", data: buildWave(waveFormatChunk(waveFormatPCM, 0, 16, false), data), want: ErrInvalidWave},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadImpulseResponse(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package effect
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// ModulatedDelay is an LFO-modulated delay line, the basis of chorus and flanger effects.
+// Each channel's LFO is offset in phase by Spread for stereo width.
+type ModulatedDelay struct {
+	// Rate is the LFO frequency in Hz
+	Rate float64
+	// Delay is the center delay time in milliseconds
+	Delay float64
+	// Depth is the amount the delay time is swept, in milliseconds
+	Depth float64
+	// Feedback is the amount of the delayed signal fed back into the delay line (-0.99-0.99)
+	Feedback float64
+	// Wet is the level of the delayed signal
+	Wet float64
+	// Dry is the level of the original signal
+	Dry float64
+	// Spread is the LFO phase offset between neighboring channels, in cycles (0-1)
+	Spread float64
+
+	sampleRate float64
+	lines      [volume.MaxChannels]delayLine
+	phase      float64
+	p          params
+}
+
+const (
+	maxModulatedDelayMs  = 50.0
+	maxModulatedFeedback = 0.99
+)
+
+// NewChorus returns a modulated delay set up as a chorus
+func NewChorus(sampleRate float64) *ModulatedDelay {
+	return newModulatedDelay("chorus", sampleRate, 0.8, 15, 5, 0, 0.5)
+}
+
+// NewFlanger returns a modulated delay set up as a flanger
+func NewFlanger(sampleRate float64) *ModulatedDelay {
+	return newModulatedDelay("flanger", sampleRate, 0.25, 3, 2, 0.7, 0.5)
+}
+
+func newModulatedDelay(name string, sampleRate float64, rate float64, delay float64, depth float64, feedback float64, wet float64) *ModulatedDelay {
+	m := &ModulatedDelay{
+		Rate:       rate,
+		Delay:      delay,
+		Depth:      depth,
+		Feedback:   feedback,
+		Wet:        wet,
+		Dry:        1,
+		Spread:     0.25,
+		sampleRate: sampleRate,
+	}
+	capacity := int(math.Ceil(2*maxModulatedDelayMs*sampleRate/1000)) + 2
+	for c := range m.lines {
+		m.lines[c] = newDelayLine(capacity)
+	}
+
+	m.p = params{effect: name}
+	m.p.add("rate", 0.01, 20, rate, &m.Rate)
+	m.p.add("delay", 0.1, maxModulatedDelayMs, delay, &m.Delay)
+	m.p.add("depth", 0, maxModulatedDelayMs, depth, &m.Depth)
+	m.p.add("feedback", -maxModulatedFeedback, maxModulatedFeedback, feedback, &m.Feedback)
+	m.p.add("wet", 0, 1, wet, &m.Wet)
+	m.p.add("dry", 0, 1, 1, &m.Dry)
+	m.p.add("spread", 0, 1, 0.25, &m.Spread)
+	return m
+}
+
+// Process applies the effect to the mix buffer in place
+func (m *ModulatedDelay) Process(buf mixing.MixBuffer) {
+	msToSamples := m.sampleRate / 1000
+	step := m.Rate / m.sampleRate
+	for i := range buf {
+		samp := &buf[i]
+		for c := 0; c < samp.Channels; c++ {
+			lfo := math.Sin(2 * math.Pi * (m.phase + float64(c)*m.Spread))
+			delay := (m.Delay + m.Depth*lfo) * msToSamples
+			x := float64(samp.StaticMatrix[c])
+			delayed := m.lines[c].readFrac(delay)
+			m.lines[c].write(x + delayed*m.Feedback)
+			samp.StaticMatrix[c] = volume.Volume(x*m.Dry + delayed*m.Wet)
+		}
+		m.phase += step
+		m.phase -= math.Floor(m.phase)
+	}
+}
+
+// Reset clears the delay lines and restarts the LFO
+func (m *ModulatedDelay) Reset() {
+	for c := range m.lines {
+		m.lines[c].reset()
+	}
+	m.phase = 0
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (m *ModulatedDelay) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the effect
+func (m *ModulatedDelay) Parameters() []mixing.ParameterInfo {
+	return m.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (m *ModulatedDelay) GetParameter(name string) (float64, error) {
+	return m.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (m *ModulatedDelay) SetParameter(name string, value float64) error {
+	return m.p.set(name, value)
+}
+
+const maxPhaserStages = 12
+
+// Phaser is a chain of LFO-swept first-order all-pass filters mixed with the original signal.
+// Each channel's LFO is offset in phase by Spread for stereo width.
+type Phaser struct {
+	// Rate is the LFO frequency in Hz
+	Rate float64
+	// MinFrequency is the lowest frequency in Hz of the all-pass sweep
+	MinFrequency float64
+	// MaxFrequency is the highest frequency in Hz of the all-pass sweep
+	MaxFrequency float64
+	// Stages is the number of all-pass filters in the chain (1-12). Values outside the range are clamped
+	Stages int
+	// Feedback is the amount of the filtered signal fed back into the chain (-0.99-0.99)
+	Feedback float64
+	// Wet is the level of the filtered signal
+	Wet float64
+	// Dry is the level of the original signal
+	Dry float64
+	// Spread is the LFO phase offset between neighboring channels, in cycles (0-1)
+	Spread float64
+
+	sampleRate float64
+	x1, y1     [volume.MaxChannels][maxPhaserStages]float64
+	last       [volume.MaxChannels]float64
+	phase      float64
+	p          params
+}
+
+// NewPhaser returns a phaser effect for the sample rate provided
+func NewPhaser(sampleRate float64) *Phaser {
+	p := &Phaser{
+		Rate:         0.5,
+		MinFrequency: 200,
+		MaxFrequency: 2000,
+		Stages:       4,
+		Feedback:     0.5,
+		Wet:          0.5,
+		Dry:          0.5,
+		Spread:       0.25,
+		sampleRate:   sampleRate,
+	}
+	nyquist := sampleRate / 2
+	p.p = params{effect: "phaser"}
+	p.p.add("rate", 0.01, 20, 0.5, &p.Rate)
+	p.p.add("minfrequency", 20, nyquist, 200, &p.MinFrequency)
+	p.p.add("maxfrequency", 20, nyquist, 2000, &p.MaxFrequency)
+	p.p.addInt("stages", 1, maxPhaserStages, 4, &p.Stages)
+	p.p.add("feedback", -maxModulatedFeedback, maxModulatedFeedback, 0.5, &p.Feedback)
+	p.p.add("wet", 0, 1, 0.5, &p.Wet)
+	p.p.add("dry", 0, 1, 0.5, &p.Dry)
+	p.p.add("spread", 0, 1, 0.25, &p.Spread)
+	return p
+}
+
+// Process applies the phaser to the mix buffer in place
+func (p *Phaser) Process(buf mixing.MixBuffer) {
+	stages := p.Stages
+	if stages < 1 {
+		stages = 1
+	} else if stages > maxPhaserStages {
+		stages = maxPhaserStages
+	}
+	minFreq := math.Max(p.MinFrequency, 1)
+	maxFreq := math.Min(math.Max(p.MaxFrequency, minFreq), p.sampleRate*0.49)
+	step := p.Rate / p.sampleRate
+
+	for i := range buf {
+		samp := &buf[i]
+		for c := 0; c < samp.Channels; c++ {
+			// sweep exponentially between the frequency limits
+			lfo := 0.5 + 0.5*math.Sin(2*math.Pi*(p.phase+float64(c)*p.Spread))
+			freq := minFreq * math.Pow(maxFreq/minFreq, lfo)
+			t := math.Tan(math.Pi * freq / p.sampleRate)
+			a := (t - 1) / (t + 1)
+
+			x := float64(samp.StaticMatrix[c])
+			y := x + p.last[c]*p.Feedback
+			for s := 0; s < stages; s++ {
+				out := a*y + p.x1[c][s] - a*p.y1[c][s]
+				p.x1[c][s] = y
+				p.y1[c][s] = out
+				y = out
+			}
+			p.last[c] = y
+			samp.StaticMatrix[c] = volume.Volume(x*p.Dry + y*p.Wet)
+		}
+		p.phase += step
+		p.phase -= math.Floor(p.phase)
+	}
+}
+
+// Reset clears the filter histories and restarts the LFO
+func (p *Phaser) Reset() {
+	for c := range p.x1 {
+		for s := range p.x1[c] {
+			p.x1[c][s] = 0
+			p.y1[c][s] = 0
+		}
+		p.last[c] = 0
+	}
+	p.phase = 0
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (p *Phaser) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the phaser
+func (p *Phaser) Parameters() []mixing.ParameterInfo {
+	return p.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (p *Phaser) GetParameter(name string) (float64, error) {
+	return p.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (p *Phaser) SetParameter(name string, value float64) error {
+	return p.p.set(name, value)
+}
@@ -0,0 +1,115 @@
+package effect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+)
+
+// maxStep returns the largest difference between neighboring samples of any channel
+func maxStep(buf mixing.MixBuffer) float64 {
+	var step float64
+	for i := 1; i < len(buf); i++ {
+		for c := 0; c < buf[i].Channels; c++ {
+			step = math.Max(step, math.Abs(float64(buf[i].StaticMatrix[c]-buf[i-1].StaticMatrix[c])))
+		}
+	}
+	return step
+}
+
+func TestModulatedDelayWithoutDepthIsPlainDelay(t *testing.T) {
+	const sampleRate = 48000
+	m := NewChorus(sampleRate)
+	m.Delay = 10
+	m.Depth = 0
+	m.Dry = 0
+	m.Wet = 1
+	buf := stereoImpulse(1000)
+	m.Process(buf)
+	if got, want := onset(buf), sampleRate*10/1000; got != want {
+		t.Errorf("onset: got %d, want %d", got, want)
+	}
+}
+
+func TestModulatedDelayWithoutClicks(t *testing.T) {
+	const (
+		sampleRate = 48000
+		freq       = 440.0
+	)
+	input := stereoSine(sampleRate, freq, 0.5, sampleRate*2)
+	inputStep := maxStep(input)
+
+	for _, tt := range []struct {
+		name string
+		m    *ModulatedDelay
+	}{
+		{"chorus", NewChorus(sampleRate)},
+		{"flanger", NewFlanger(sampleRate)},
+	} {
+		tt.m.Rate = 5
+		buf := stereoSine(sampleRate, freq, 0.5, sampleRate*2)
+		tt.m.Process(buf)
+
+		// a swept delay shifts the pitch by at most the sweep rate, so the slope of
+		// the output can't be much steeper than the input's scaled by the total gain
+		sweep := 2 * math.Pi * tt.m.Rate * tt.m.Depth / 1000
+		gain := tt.m.Dry + tt.m.Wet/(1-math.Abs(tt.m.Feedback))
+		if got, limit := maxStep(buf), inputStep*gain*(1+sweep); got > limit {
+			t.Errorf("%s: largest step %v exceeds %v", tt.name, got, limit)
+		}
+
+		// the modulation must change the signal over time, rather than act as a fixed comb filter
+		var diff float64
+		period := int(sampleRate / freq * 11)
+		for i := sampleRate; i < sampleRate+period; i++ {
+			diff = math.Max(diff, math.Abs(float64(buf[i].StaticMatrix[0]-buf[i+sampleRate/10].StaticMatrix[0])))
+		}
+		if diff < 0.01 {
+			t.Errorf("%s: output does not vary with the LFO", tt.name)
+		}
+	}
+}
+
+func TestPhaserWithoutClicks(t *testing.T) {
+	const sampleRate = 48000
+	p := NewPhaser(sampleRate)
+	p.Rate = 5
+	p.Feedback = 0
+	buf := stereoSine(sampleRate, 440, 0.5, sampleRate)
+	inputStep := maxStep(stereoSine(sampleRate, 440, 0.5, sampleRate))
+	p.Process(buf)
+	// all-pass filters don't change the level, so the output is bounded by dry+wet
+	if got, limit := maxStep(buf), inputStep*(p.Dry+p.Wet)*1.1; got > limit {
+		t.Errorf("largest step %v exceeds %v", got, limit)
+	}
+}
+
+func TestPhaserStages(t *testing.T) {
+	p := NewPhaser(48000)
+	for _, tt := range []struct {
+		value float64
+		want  int
+	}{
+		{3.4, 3},
+		{0, 1},
+		{40, maxPhaserStages},
+	} {
+		if err := p.SetParameter("stages", tt.value); err != nil {
+			t.Fatalf("SetParameter(stages, %v): %v", tt.value, err)
+		}
+		if p.Stages != tt.want {
+			t.Errorf("SetParameter(stages, %v): got %d, want %d", tt.value, p.Stages, tt.want)
+		}
+	}
+
+	// out of range values set directly are clamped when processing
+	p.Stages = 100
+	buf := stereoSine(48000, 440, 0.5, 1000)
+	p.Process(buf)
+	for i, s := range buf {
+		if v := float64(s.StaticMatrix[0]); math.IsNaN(v) || math.Abs(v) > 1 {
+			t.Fatalf("sample %d with too many stages: got %v", i, v)
+		}
+	}
+}
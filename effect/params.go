@@ -0,0 +1,103 @@
+package effect
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gotracker/gomixing/mixing"
+)
+
+// param binds a parameter description to the value it controls
+type param struct {
+	mixing.ParameterInfo
+	get func() float64
+	set func(float64)
+}
+
+// params is a table of parameters for an effect
+type params struct {
+	effect string
+	list   []param
+}
+
+func (p *params) add(name string, minValue float64, maxValue float64, def float64, value *float64) {
+	p.list = append(p.list, param{
+		ParameterInfo: mixing.ParameterInfo{
+			Name:    name,
+			Min:     minValue,
+			Max:     maxValue,
+			Default: def,
+		},
+		get: func() float64 { return *value },
+		set: func(v float64) { *value = v },
+	})
+}
+
+func (p *params) addBool(name string, def bool, value *bool) {
+	var d float64
+	if def {
+		d = 1
+	}
+	p.list = append(p.list, param{
+		ParameterInfo: mixing.ParameterInfo{
+			Name:    name,
+			Min:     0,
+			Max:     1,
+			Default: d,
+		},
+		get: func() float64 {
+			if *value {
+				return 1
+			}
+			return 0
+		},
+		set: func(v float64) { *value = v >= 0.5 },
+	})
+}
+
+func (p *params) addInt(name string, minValue int, maxValue int, def int, value *int) {
+	p.list = append(p.list, param{
+		ParameterInfo: mixing.ParameterInfo{
+			Name:    name,
+			Min:     float64(minValue),
+			Max:     float64(maxValue),
+			Default: float64(def),
+		},
+		get: func() float64 { return float64(*value) },
+		set: func(v float64) { *value = int(math.Round(v)) },
+	})
+}
+
+func (p params) infos() []mixing.ParameterInfo {
+	infos := make([]mixing.ParameterInfo, len(p.list))
+	for i, pi := range p.list {
+		infos[i] = pi.ParameterInfo
+	}
+	return infos
+}
+
+func (p params) get(name string) (float64, error) {
+	for _, pi := range p.list {
+		if pi.Name == name {
+			return pi.get(), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, p.effect, name)
+}
+
+// set assigns a parameter, clamping it to the parameter's range
+func (p params) set(name string, value float64) error {
+	for _, pi := range p.list {
+		if pi.Name != name {
+			continue
+		}
+		if value < pi.Min {
+			value = pi.Min
+		} else if value > pi.Max {
+			value = pi.Max
+		}
+		pi.set(value)
+		return nil
+	}
+	return fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, p.effect, name)
+}
@@ -0,0 +1,36 @@
+package effect
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+)
+
+func TestParams(t *testing.T) {
+	var level float64
+	var on bool
+	p := params{effect: "test"}
+	p.add("level", -1, 1, 0.5, &level)
+	p.addBool("on", true, &on)
+
+	if err := p.set("level", 3); err != nil {
+		t.Fatalf("set level: %v", err)
+	}
+	if got, _ := p.get("level"); got != 1 {
+		t.Errorf("level set above max: got %v, want 1", got)
+	}
+	if err := p.set("on", 0.7); err != nil {
+		t.Fatalf("set on: %v", err)
+	}
+	if !on {
+		t.Errorf("on: got false, want true")
+	}
+
+	if _, err := p.get("missing"); !errors.Is(err, mixing.ErrUnknownParameter) {
+		t.Errorf("get missing: got %v, want %v", err, mixing.ErrUnknownParameter)
+	}
+	if err := p.set("missing", 0); !errors.Is(err, mixing.ErrUnknownParameter) {
+		t.Errorf("set missing: got %v, want %v", err, mixing.ErrUnknownParameter)
+	}
+}
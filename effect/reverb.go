@@ -0,0 +1,223 @@
+package effect
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Freeverb tuning values, in samples at 44.1kHz
+var (
+	reverbCombTuning    = [...]int{1116, 1188, 1277, 1356, 1422, 1491, 1557, 1617}
+	reverbAllPassTuning = [...]int{556, 441, 341, 225}
+)
+
+const (
+	reverbTuningRate    = 44100.0
+	reverbStereoSpread  = 23
+	reverbFixedGain     = 0.015
+	reverbScaleWet      = 3.0
+	reverbScaleDamping  = 0.4
+	reverbScaleRoom     = 0.28
+	reverbOffsetRoom    = 0.7
+	reverbAllPassFeed   = 0.5
+	reverbMaxPreDelayMs = 500.0
+)
+
+// Reverb is a Schroeder-Moorer style algorithmic reverb (as popularized by Freeverb),
+// made of parallel damped comb filters followed by series all-pass filters.
+// Stereo and quadraphonic buffers are processed as left/right pairs; mono buffers
+// use a single network.
+type Reverb struct {
+	// RoomSize is the size of the simulated room (0-1)
+	RoomSize float64
+	// Damping is the amount of high frequency absorption (0-1)
+	Damping float64
+	// Wet is the level of the reverberated signal (0-1)
+	Wet float64
+	// Dry is the level of the original signal (0-1)
+	Dry float64
+	// Width is the stereo width of the reverberated signal (0-1)
+	Width float64
+	// PreDelay is the delay in milliseconds before the reverberated signal starts
+	PreDelay float64
+
+	sampleRate float64
+	pairs      [volume.MaxChannels / 2]reverbPair
+	p          params
+}
+
+type reverbPair struct {
+	left, right reverbNetwork
+	preDelay    delayLine
+}
+
+type reverbNetwork struct {
+	combs     [len(reverbCombTuning)]reverbComb
+	allPasses [len(reverbAllPassTuning)]reverbAllPass
+}
+
+type reverbComb struct {
+	buf   []float64
+	idx   int
+	store float64
+}
+
+type reverbAllPass struct {
+	buf []float64
+	idx int
+}
+
+// NewReverb returns a reverb effect for the sample rate provided
+func NewReverb(sampleRate float64) *Reverb {
+	r := &Reverb{
+		RoomSize:   0.5,
+		Damping:    0.5,
+		Wet:        1.0 / reverbScaleWet,
+		Dry:        1,
+		Width:      1,
+		sampleRate: sampleRate,
+	}
+	scale := sampleRate / reverbTuningRate
+	for p := range r.pairs {
+		spread := 2 * p * reverbStereoSpread
+		r.pairs[p].left = newReverbNetwork(scale, spread)
+		r.pairs[p].right = newReverbNetwork(scale, spread+reverbStereoSpread)
+		r.pairs[p].preDelay = newDelayLine(int(math.Ceil(reverbMaxPreDelayMs*sampleRate/1000)) + 1)
+	}
+
+	r.p = params{effect: "reverb"}
+	r.p.add("roomsize", 0, 1, 0.5, &r.RoomSize)
+	r.p.add("damping", 0, 1, 0.5, &r.Damping)
+	r.p.add("wet", 0, 1, 1.0/reverbScaleWet, &r.Wet)
+	r.p.add("dry", 0, 1, 1, &r.Dry)
+	r.p.add("width", 0, 1, 1, &r.Width)
+	r.p.add("predelay", 0, reverbMaxPreDelayMs, 0, &r.PreDelay)
+	return r
+}
+
+func newReverbNetwork(scale float64, spread int) reverbNetwork {
+	var n reverbNetwork
+	for i, t := range reverbCombTuning {
+		n.combs[i].buf = make([]float64, scaledLength(t+spread, scale))
+	}
+	for i, t := range reverbAllPassTuning {
+		n.allPasses[i].buf = make([]float64, scaledLength(t+spread, scale))
+	}
+	return n
+}
+
+func scaledLength(samples int, scale float64) int {
+	n := int(math.Round(float64(samples) * scale))
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+func (c *reverbComb) process(x float64, feedback float64, damp float64) float64 {
+	out := c.buf[c.idx]
+	c.store = out*(1-damp) + c.store*damp
+	c.buf[c.idx] = x + c.store*feedback
+	c.idx++
+	if c.idx >= len(c.buf) {
+		c.idx = 0
+	}
+	return out
+}
+
+func (a *reverbAllPass) process(x float64) float64 {
+	delayed := a.buf[a.idx]
+	a.buf[a.idx] = x + delayed*reverbAllPassFeed
+	a.idx++
+	if a.idx >= len(a.buf) {
+		a.idx = 0
+	}
+	return delayed - x
+}
+
+func (n *reverbNetwork) process(x float64, feedback float64, damp float64) float64 {
+	var out float64
+	for i := range n.combs {
+		out += n.combs[i].process(x, feedback, damp)
+	}
+	for i := range n.allPasses {
+		out = n.allPasses[i].process(out)
+	}
+	return out
+}
+
+func (n *reverbNetwork) reset() {
+	for i := range n.combs {
+		clearFloats(n.combs[i].buf)
+		n.combs[i].idx = 0
+		n.combs[i].store = 0
+	}
+	for i := range n.allPasses {
+		clearFloats(n.allPasses[i].buf)
+		n.allPasses[i].idx = 0
+	}
+}
+
+// Process applies the reverb to the mix buffer in place
+func (r *Reverb) Process(buf mixing.MixBuffer) {
+	feedback := r.RoomSize*reverbScaleRoom + reverbOffsetRoom
+	damp := r.Damping * reverbScaleDamping
+	wet := r.Wet * reverbScaleWet
+	wet1 := wet * (r.Width/2 + 0.5)
+	wet2 := wet * ((1 - r.Width) / 2)
+	preDelay := int(math.Round(r.PreDelay * r.sampleRate / 1000))
+
+	for i := range buf {
+		samp := &buf[i]
+		if samp.Channels == 1 {
+			pair := &r.pairs[0]
+			x := float64(samp.StaticMatrix[0])
+			in := pair.preDelay.process(x*2*reverbFixedGain, preDelay)
+			out := pair.left.process(in, feedback, damp)
+			samp.StaticMatrix[0] = volume.Volume(x*r.Dry + out*wet)
+			continue
+		}
+
+		for p := 0; p+1 < samp.Channels; p += 2 {
+			pair := &r.pairs[p/2]
+			l := float64(samp.StaticMatrix[p])
+			rt := float64(samp.StaticMatrix[p+1])
+			in := pair.preDelay.process((l+rt)*reverbFixedGain, preDelay)
+			outL := pair.left.process(in, feedback, damp)
+			outR := pair.right.process(in, feedback, damp)
+			samp.StaticMatrix[p] = volume.Volume(l*r.Dry + outL*wet1 + outR*wet2)
+			samp.StaticMatrix[p+1] = volume.Volume(rt*r.Dry + outR*wet1 + outL*wet2)
+		}
+	}
+}
+
+// Reset clears the reverb's delay lines
+func (r *Reverb) Reset() {
+	for p := range r.pairs {
+		r.pairs[p].left.reset()
+		r.pairs[p].right.reset()
+		r.pairs[p].preDelay.reset()
+	}
+}
+
+// Latency returns the number of samples the effect delays its output by
+func (r *Reverb) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the reverb
+func (r *Reverb) Parameters() []mixing.ParameterInfo {
+	return r.p.infos()
+}
+
+// GetParameter returns the current value of a parameter
+func (r *Reverb) GetParameter(name string) (float64, error) {
+	return r.p.get(name)
+}
+
+// SetParameter sets the value of a parameter
+func (r *Reverb) SetParameter(name string, value float64) error {
+	return r.p.set(name, value)
+}
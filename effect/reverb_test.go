@@ -0,0 +1,124 @@
+package effect
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// stereoImpulse returns a stereo mix buffer with a unit impulse in both channels at the start
+func stereoImpulse(n int) mixing.MixBuffer {
+	buf := make(mixing.MixBuffer, n)
+	for i := range buf {
+		buf[i] = volume.Matrix{Channels: 2}
+	}
+	buf[0].StaticMatrix = volume.StaticMatrix{1, 1}
+	return buf
+}
+
+// windowEnergy returns the energy of the first channel in consecutive windows of `size` samples
+func windowEnergy(buf mixing.MixBuffer, size int) []float64 {
+	var energy []float64
+	for start := 0; start+size <= len(buf); start += size {
+		var e float64
+		for _, s := range buf[start : start+size] {
+			v := float64(s.StaticMatrix[0])
+			e += v * v
+		}
+		energy = append(energy, e)
+	}
+	return energy
+}
+
+// onset returns the index of the first sample with a non-zero first channel, or -1
+func onset(buf mixing.MixBuffer) int {
+	for i, s := range buf {
+		if s.StaticMatrix[0] != 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestReverbTailDecays(t *testing.T) {
+	const sampleRate = 44100
+	window := sampleRate / 10
+
+	var tails [2]float64
+	for i, room := range []float64{0.2, 0.9} {
+		r := NewReverb(sampleRate)
+		r.Dry = 0
+		r.RoomSize = room
+		buf := stereoImpulse(sampleRate * 3)
+		r.Process(buf)
+
+		energy := windowEnergy(buf, window)
+		if energy[1] == 0 {
+			t.Fatalf("room %v: no reverb tail", room)
+		}
+		// skip the build-up in the first window, then the tail must keep falling
+		for w := 2; w < len(energy); w++ {
+			if energy[w] >= energy[w-1] {
+				t.Errorf("room %v: energy rose from %g to %g in window %d", room, energy[w-1], energy[w], w)
+			}
+		}
+		if last := energy[len(energy)-1]; last > energy[1]*1e-3 {
+			t.Errorf("room %v: tail energy %g has not decayed from %g", room, last, energy[1])
+		}
+		tails[i] = energy[len(energy)/2] / energy[1]
+	}
+	if tails[1] <= tails[0] {
+		t.Errorf("a bigger room should ring longer: got %g, smaller room %g", tails[1], tails[0])
+	}
+}
+
+func TestReverbSilentAfterReset(t *testing.T) {
+	r := NewReverb(44100)
+	r.Process(stereoImpulse(4410))
+	r.Reset()
+
+	buf := make(mixing.MixBuffer, 44100)
+	for i := range buf {
+		buf[i] = volume.Matrix{Channels: 2}
+	}
+	r.Process(buf)
+	for i, s := range buf {
+		if s.StaticMatrix[0] != 0 || s.StaticMatrix[1] != 0 {
+			t.Fatalf("sample %d after reset: got %v, want silence", i, s.StaticMatrix[:2])
+		}
+	}
+}
+
+func TestReverbPreDelayAndDry(t *testing.T) {
+	const sampleRate = 48000
+	r := NewReverb(sampleRate)
+	r.Dry = 0
+	buf := stereoImpulse(sampleRate / 2)
+	r.Process(buf)
+	base := onset(buf)
+	if base <= 0 {
+		t.Fatalf("onset without pre-delay: got %d", base)
+	}
+
+	r.Reset()
+	r.PreDelay = 25
+	buf = stereoImpulse(sampleRate / 2)
+	r.Process(buf)
+	if got, want := onset(buf), base+sampleRate*25/1000; got != want {
+		t.Errorf("onset with 25ms pre-delay: got %d, want %d", got, want)
+	}
+
+	r.Reset()
+	r.Dry = 1
+	r.Wet = 0
+	buf = stereoSine(sampleRate, 440, 0.5, 1000)
+	want := stereoSine(sampleRate, 440, 0.5, 1000)
+	r.Process(buf)
+	for i := range buf {
+		if math.Abs(float64(buf[i].StaticMatrix[0]-want[i].StaticMatrix[0])) > 1e-9 {
+			t.Fatalf("dry only, sample %d: got %v, want %v", i, buf[i].StaticMatrix[0], want[i].StaticMatrix[0])
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package envelope
+
+type adsrStage int
+
+const (
+	adsrAttack = adsrStage(iota)
+	adsrDecay
+	adsrSustain
+	adsrRelease
+	adsrOff
+)
+
+// ADSR is a classic attack-decay-sustain-release envelope with linear segments. Times are in steps
+type ADSR struct {
+	// AttackTime is the time taken to rise from silence to full level
+	AttackTime float64
+	// DecayTime is the time taken to fall from full level to silence; the fall stops at the sustain level
+	DecayTime float64
+	// SustainLevel is the level (0-1) held while the key is down
+	SustainLevel float64
+	// ReleaseTime is the time taken to fall from full level to silence after the key is released
+	ReleaseTime float64
+
+	stage adsrStage
+	level float64
+}
+
+// NewADSR returns an ADSR envelope with the times (in steps) and sustain level provided
+func NewADSR(attack float64, decay float64, sustain float64, release float64) *ADSR {
+	return &ADSR{
+		AttackTime:   attack,
+		DecayTime:    decay,
+		SustainLevel: sustain,
+		ReleaseTime:  release,
+	}
+}
+
+// Value returns the level at the current step
+func (e *ADSR) Value() float64 {
+	return e.level
+}
+
+// Advance moves on by one step
+func (e *ADSR) Advance() {
+	switch e.stage {
+	case adsrAttack:
+		e.level += rate(e.AttackTime)
+		if e.level >= 1 {
+			e.level = 1
+			e.stage = adsrDecay
+		}
+	case adsrDecay:
+		e.level -= rate(e.DecayTime)
+		if e.level <= e.SustainLevel {
+			e.level = e.SustainLevel
+			e.stage = adsrSustain
+		}
+	case adsrRelease:
+		e.level -= rate(e.ReleaseTime)
+		if e.level <= 0 {
+			e.level = 0
+			e.stage = adsrOff
+		}
+	}
+}
+
+// Release moves the envelope into its release stage
+func (e *ADSR) Release() {
+	if e.stage != adsrOff {
+		e.stage = adsrRelease
+	}
+}
+
+// IsEnded returns true once the release has finished
+func (e *ADSR) IsEnded() bool {
+	return e.stage == adsrOff
+}
+
+// Reset restarts the envelope from silence
+func (e *ADSR) Reset() {
+	e.stage = adsrAttack
+	e.level = 0
+}
+
+// rate returns the change in level per step for a full-scale segment of the time provided
+func rate(time float64) float64 {
+	if time <= 1 {
+		return 1
+	}
+	return 1 / time
+}
@@ -0,0 +1,64 @@
+package envelope
+
+import (
+	"math"
+	"testing"
+)
+
+func TestADSR(t *testing.T) {
+	e := NewADSR(4, 8, 0.5, 8)
+	want := []float64{0, 0.25, 0.5, 0.75, 1, 0.875, 0.75, 0.625, 0.5, 0.5, 0.5}
+	for i, w := range want {
+		if got := e.Value(); math.Abs(got-w) > 1e-12 {
+			t.Errorf("step %d: got %v, want %v", i, got, w)
+		}
+		e.Advance()
+	}
+
+	e.Release()
+	for i := 0; i < 4; i++ {
+		e.Advance()
+	}
+	if got := e.Value(); got != 0 || !e.IsEnded() {
+		t.Errorf("after release: value %v, ended %v", got, e.IsEnded())
+	}
+
+	e.Reset()
+	if e.Value() != 0 || e.IsEnded() {
+		t.Errorf("after reset: value %v, ended %v", e.Value(), e.IsEnded())
+	}
+}
+
+func TestADSRReleaseDuringAttack(t *testing.T) {
+	e := NewADSR(10, 0, 1, 4)
+	for i := 0; i < 4; i++ {
+		e.Advance()
+	}
+	e.Release()
+	steps := 0
+	for !e.IsEnded() {
+		e.Advance()
+		steps++
+	}
+	// the release falls at its full-scale rate from wherever the attack had got to
+	if steps != 2 {
+		t.Errorf("release from 0.4 took %d steps, want 2", steps)
+	}
+}
+
+func TestADSRInstantTimes(t *testing.T) {
+	e := NewADSR(0, 0, 0.3, 0)
+	e.Advance()
+	if got := e.Value(); got != 1 {
+		t.Errorf("instant attack: got %v, want 1", got)
+	}
+	e.Advance()
+	if got := e.Value(); got != 0.3 {
+		t.Errorf("instant decay: got %v, want 0.3", got)
+	}
+	e.Release()
+	e.Advance()
+	if !e.IsEnded() {
+		t.Error("instant release did not end the envelope")
+	}
+}
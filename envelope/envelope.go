@@ -0,0 +1,43 @@
+package envelope
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Generator is a source of values that change over time. Each call to Advance moves it on by
+// one step, which may be an output sample or a tracker tick depending on how it is driven.
+type Generator interface {
+	// Value returns the value at the current step
+	Value() float64
+	// Advance moves on by one step
+	Advance()
+	// Release releases the key, leaving any sustain
+	Release()
+	// IsEnded returns true once the envelope can no longer change
+	IsEnded() bool
+	// Reset restarts the envelope from the beginning with the key down
+	Reset()
+}
+
+// Volume converts the value of a volume envelope (0-1) into a volume
+func Volume(g Generator) volume.Volume {
+	return volume.Volume(math.Max(0, math.Min(g.Value(), 1)))
+}
+
+// Pan moves a panning position by the value of a panning envelope (-1 for full left to 1 for full right).
+// As in XM and IT, the envelope's range narrows as the position moves away from center,
+// so the result never goes past either side
+func Pan(base panning.Position, g Generator) panning.Position {
+	p := float64(panning.FromStereoPosition(base, 0, 1))
+	v := math.Max(-1, math.Min(g.Value(), 1))
+	p += v * (0.5 - math.Abs(p-0.5))
+	return panning.MakeStereoPosition(float32(p), 0, 1)
+}
+
+// PitchRatio converts the value of a pitch envelope in semitones into a playback rate multiplier
+func PitchRatio(g Generator) float64 {
+	return math.Pow(2, g.Value()/12)
+}
@@ -0,0 +1,159 @@
+package envelope
+
+import "math"
+
+// Point is a node of a point envelope
+type Point struct {
+	// Pos is the step at which the envelope reaches the point
+	Pos int
+	// Value is the value of the envelope at the point
+	Value float64
+	// Curve shapes the segment leading to the next point. Zero is a straight line,
+	// positive values start slowly and finish quickly, and negative values do the opposite
+	Curve float64
+}
+
+// Points is an envelope made of points joined by segments, as used by tracker instruments.
+// While the key is down, the sustain range repeats; a sustain range of a single point holds it.
+// The loop range repeats after the key is released, and while the key is down when LoopDuringSustain
+// is set. Past the last point, the envelope holds the last point's value.
+type Points struct {
+	Points []Point
+	// SustainStart and SustainEnd are the indices of the points bounding the sustain range, or -1 for none
+	SustainStart int
+	SustainEnd   int
+	// LoopStart and LoopEnd are the indices of the points bounding the loop range, or -1 for none
+	LoopStart int
+	LoopEnd   int
+	// LoopDuringSustain applies the loop range while the key is down, as FastTracker 2 does, so that a
+	// loop ending before the sustain point repeats forever. Otherwise the loop is ignored until the key
+	// is released, as in Impulse Tracker
+	LoopDuringSustain bool
+
+	tick     int
+	released bool
+}
+
+// NewPoints returns a point envelope with no sustain or loop. Points must be in order of position
+func NewPoints(points []Point) *Points {
+	return &Points{
+		Points:       points,
+		SustainStart: -1,
+		SustainEnd:   -1,
+		LoopStart:    -1,
+		LoopEnd:      -1,
+	}
+}
+
+// SetSustain sets the sustain range to the points provided
+func (e *Points) SetSustain(start int, end int) {
+	e.SustainStart = start
+	e.SustainEnd = end
+}
+
+// SetLoop sets the loop range to the points provided
+func (e *Points) SetLoop(start int, end int) {
+	e.LoopStart = start
+	e.LoopEnd = end
+}
+
+// Pos returns the current step of the envelope
+func (e *Points) Pos() int {
+	return e.tick
+}
+
+// SetPos moves the envelope to a step, as tracker envelope position effects do
+func (e *Points) SetPos(pos int) {
+	e.tick = pos
+}
+
+// Value returns the value at the current step
+func (e *Points) Value() float64 {
+	n := len(e.Points)
+	if n == 0 {
+		return 0
+	}
+	if e.tick <= e.Points[0].Pos {
+		return e.Points[0].Value
+	}
+	for i := 1; i < n; i++ {
+		b := e.Points[i]
+		if e.tick >= b.Pos {
+			continue
+		}
+		a := e.Points[i-1]
+		t := float64(e.tick-a.Pos) / float64(b.Pos-a.Pos)
+		return a.Value + (b.Value-a.Value)*shape(t, a.Curve)
+	}
+	return e.Points[n-1].Value
+}
+
+// shape bends a position (0-1) along a segment by the curve amount
+func shape(t float64, curve float64) float64 {
+	if curve == 0 {
+		return t
+	}
+	return math.Expm1(curve*t) / math.Expm1(curve)
+}
+
+// Advance moves on by one step, jumping back at the end of an active sustain or loop range
+func (e *Points) Advance() {
+	if len(e.Points) == 0 {
+		return
+	}
+	e.tick++
+	sustained := !e.released && e.validRange(e.SustainStart, e.SustainEnd)
+	looped := e.validRange(e.LoopStart, e.LoopEnd)
+	if looped && (!sustained || e.LoopDuringSustain) {
+		if end := e.Points[e.LoopEnd].Pos; e.tick > end {
+			e.tick = e.Points[e.LoopStart].Pos
+			if e.LoopStart == e.LoopEnd {
+				e.tick = end
+			}
+		}
+	}
+	if sustained {
+		if end := e.Points[e.SustainEnd].Pos; e.tick > end {
+			e.tick = e.Points[e.SustainStart].Pos
+			if e.SustainStart == e.SustainEnd {
+				e.tick = end
+			}
+		}
+		return
+	}
+	if looped {
+		return
+	}
+	if last := e.Points[len(e.Points)-1].Pos; e.tick > last {
+		e.tick = last
+	}
+}
+
+func (e *Points) validRange(start int, end int) bool {
+	return start >= 0 && end >= start && end < len(e.Points)
+}
+
+// Release leaves the sustain range
+func (e *Points) Release() {
+	e.released = true
+}
+
+// IsEnded returns true once the envelope has reached its last point with no range left to repeat
+func (e *Points) IsEnded() bool {
+	if len(e.Points) == 0 {
+		return true
+	}
+	if !e.released && e.validRange(e.SustainStart, e.SustainEnd) {
+		return false
+	}
+	if e.validRange(e.LoopStart, e.LoopEnd) {
+		return false
+	}
+	return e.tick >= e.Points[len(e.Points)-1].Pos
+}
+
+// Reset restarts the envelope from its first step with the key down
+func (e *Points) Reset() {
+	e.tick = 0
+	e.released = false
+}
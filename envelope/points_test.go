@@ -0,0 +1,142 @@
+package envelope
+
+import (
+	"reflect"
+	"testing"
+)
+
+// ticks returns the envelope position after each of `n` steps
+func ticks(e *Points, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		e.Advance()
+		out[i] = e.Pos()
+	}
+	return out
+}
+
+func testPoints() *Points {
+	return NewPoints([]Point{
+		{Pos: 0, Value: 0},
+		{Pos: 2, Value: 1},
+		{Pos: 4, Value: 0.5},
+		{Pos: 6, Value: 0.75},
+		{Pos: 8, Value: 0.25},
+		{Pos: 10, Value: 0},
+	})
+}
+
+func TestPointsValue(t *testing.T) {
+	e := testPoints()
+	want := []float64{0, 0.5, 1, 0.75, 0.5, 0.625, 0.75, 0.5, 0.25, 0.125, 0, 0, 0}
+	for i, w := range want {
+		if got := e.Value(); got != w {
+			t.Errorf("step %d: got %v, want %v", i, got, w)
+		}
+		e.Advance()
+	}
+	if !e.IsEnded() {
+		t.Error("envelope did not end after its last point")
+	}
+}
+
+func TestPointsCurve(t *testing.T) {
+	e := NewPoints([]Point{{Pos: 0, Value: 0, Curve: 3}, {Pos: 10, Value: 1}})
+	e.SetPos(5)
+	if got := e.Value(); got <= 0 || got >= 0.5 {
+		t.Errorf("positive curve midpoint: got %v, want below a straight line", got)
+	}
+	e.Points[0].Curve = -3
+	if got := e.Value(); got <= 0.5 || got >= 1 {
+		t.Errorf("negative curve midpoint: got %v, want above a straight line", got)
+	}
+}
+
+func TestPointsSustain(t *testing.T) {
+	e := testPoints()
+	e.SetSustain(2, 2)
+	if got, want := ticks(e, 6), []int{1, 2, 3, 4, 4, 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sustain point: got %v, want %v", got, want)
+	}
+	if e.IsEnded() {
+		t.Error("sustained envelope reported ended")
+	}
+	e.Release()
+	if got, want := ticks(e, 8), []int{5, 6, 7, 8, 9, 10, 10, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after release: got %v, want %v", got, want)
+	}
+
+	e.Reset()
+	e.SetSustain(1, 2)
+	if got, want := ticks(e, 8), []int{1, 2, 3, 4, 2, 3, 4, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sustain range: got %v, want %v", got, want)
+	}
+}
+
+func TestPointsLoopBeforeSustain(t *testing.T) {
+	// a loop over points 0-2 with the sustain on point 4
+	tests := []struct {
+		name              string
+		loopDuringSustain bool
+		keyDown           []int
+		released          []int
+	}{
+		{
+			// Impulse Tracker ignores the loop until release, so the sustain point is reached
+			name:     "it",
+			keyDown:  []int{1, 2, 3, 4, 5, 6, 7, 8, 8, 8},
+			released: []int{0, 1, 2, 3, 4, 0, 1, 2, 3, 4},
+		},
+		{
+			// FastTracker 2 loops while the key is down too, so the sustain point is never reached
+			name:              "ft2",
+			loopDuringSustain: true,
+			keyDown:           []int{1, 2, 3, 4, 0, 1, 2, 3, 4, 0},
+			released:          []int{1, 2, 3, 4, 0, 1, 2, 3, 4, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewPoints([]Point{
+				{Pos: 0}, {Pos: 2}, {Pos: 4}, {Pos: 6}, {Pos: 8}, {Pos: 20},
+			})
+			e.SetLoop(0, 2)
+			e.SetSustain(4, 4)
+			e.LoopDuringSustain = tt.loopDuringSustain
+			if got := ticks(e, 10); !reflect.DeepEqual(got, tt.keyDown) {
+				t.Errorf("key down: got %v, want %v", got, tt.keyDown)
+			}
+			e.Release()
+			if got := ticks(e, 10); !reflect.DeepEqual(got, tt.released) {
+				t.Errorf("released: got %v, want %v", got, tt.released)
+			}
+			if e.IsEnded() {
+				t.Error("looping envelope reported ended")
+			}
+		})
+	}
+}
+
+func TestPointsLoopAfterSustain(t *testing.T) {
+	for _, loopDuringSustain := range []bool{false, true} {
+		e := testPoints()
+		e.SetSustain(1, 1)
+		e.SetLoop(3, 4)
+		e.LoopDuringSustain = loopDuringSustain
+		if got, want := ticks(e, 4), []int{1, 2, 2, 2}; !reflect.DeepEqual(got, want) {
+			t.Errorf("loop during sustain %v, key down: got %v, want %v", loopDuringSustain, got, want)
+		}
+		e.Release()
+		if got, want := ticks(e, 9), []int{3, 4, 5, 6, 7, 8, 6, 7, 8}; !reflect.DeepEqual(got, want) {
+			t.Errorf("loop during sustain %v, released: got %v, want %v", loopDuringSustain, got, want)
+		}
+	}
+}
+
+func TestPointsEmpty(t *testing.T) {
+	e := NewPoints(nil)
+	e.Advance()
+	if e.Value() != 0 || !e.IsEnded() {
+		t.Errorf("empty envelope: value %v, ended %v", e.Value(), e.IsEnded())
+	}
+}
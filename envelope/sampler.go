@@ -0,0 +1,132 @@
+package envelope
+
+import (
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Sampler plays a sample stream with envelopes applied to every output sample.
+// The volume envelope scales the samples, the pitch envelope scales the playback rate, and
+// when a pan mixer is set, the panning envelope pans the samples into the mixer's channels.
+// Any envelope may be nil. To run tick-based envelopes, set SamplesPerStep to the tick length.
+type Sampler struct {
+	// Period is the base amount the stream position moves per output sample
+	Period float32
+	// Volume is the volume envelope (0-1)
+	Volume Generator
+	// Pitch is the pitch envelope in semitones
+	Pitch Generator
+	// Pan is the panning envelope (-1 to 1), applied around BasePan
+	Pan Generator
+	// BasePan is the panning position the panning envelope moves from
+	BasePan panning.Position
+	// PanMixer pans the samples when the panning envelope is set
+	PanMixer mixing.PanMixer
+	// SamplesPerStep is the number of output samples per envelope step. Zero or one steps every sample
+	SamplesPerStep int
+
+	ss       sampling.SampleStream
+	pos      sampling.Pos
+	stepLeft int
+	stepFor  int
+}
+
+// NewSampler returns a sampler that plays the stream from `pos`, moving `period` positions per output sample
+func NewSampler(ss sampling.SampleStream, pos sampling.Pos, period float32) *Sampler {
+	return &Sampler{
+		Period:  period,
+		BasePan: panning.CenterAhead,
+		ss:      ss,
+		pos:     pos,
+	}
+}
+
+// GetPosition returns the position in the stream
+func (s *Sampler) GetPosition() sampling.Pos {
+	return s.pos
+}
+
+// GetSample returns the current sample with the volume and panning envelopes applied
+func (s *Sampler) GetSample() volume.Matrix {
+	if s.ss == nil {
+		return volume.Matrix{}
+	}
+	samp := s.ss.GetSample(s.pos)
+	if s.Volume != nil {
+		samp = samp.Apply(Volume(s.Volume))
+	}
+	if s.Pan != nil && s.PanMixer != nil {
+		samp = s.PanMixer.GetMixingMatrix(Pan(s.BasePan, s.Pan)).ApplyToMatrix(samp)
+	}
+	return samp
+}
+
+// VolMatrix returns the matrix to mix the sampler's output with. Samples panned by the
+// panning envelope are already in the pan mixer's channels, so they are mixed in at unity gain
+func (s *Sampler) VolMatrix(panmixer mixing.PanMixer, pan panning.Position) volume.Matrix {
+	if s.Pan == nil || s.PanMixer == nil {
+		return panmixer.GetMixingMatrix(pan)
+	}
+	mtx := volume.Matrix{Channels: s.PanMixer.NumChannels()}
+	for c := 0; c < mtx.Channels; c++ {
+		mtx.StaticMatrix[c] = 1
+	}
+	return mtx
+}
+
+// Advance moves the stream position on by the period scaled by the pitch envelope,
+// then steps the envelopes when a step has elapsed
+func (s *Sampler) Advance() {
+	period := s.Period
+	if s.Pitch != nil {
+		period *= float32(PitchRatio(s.Pitch))
+	}
+	s.pos.Add(period)
+
+	if s.stepFor != s.SamplesPerStep {
+		// a new step length starts a full step from here
+		s.stepFor = s.SamplesPerStep
+		s.stepLeft = s.SamplesPerStep
+	}
+	s.stepLeft--
+	if s.stepLeft > 0 {
+		return
+	}
+	s.stepLeft = s.SamplesPerStep
+	for _, g := range []Generator{s.Volume, s.Pitch, s.Pan} {
+		if g != nil {
+			g.Advance()
+		}
+	}
+}
+
+// Release releases the key of all of the envelopes, and of the stream if it is a releaser
+func (s *Sampler) Release() {
+	for _, g := range []Generator{s.Volume, s.Pitch, s.Pan} {
+		if g != nil {
+			g.Release()
+		}
+	}
+	if r, ok := s.ss.(sampling.Releaser); ok {
+		r.Release()
+	}
+}
+
+// IsEnded returns true once the volume envelope has finished at silence,
+// or the stream reports that it has ended or the position is past its length
+func (s *Sampler) IsEnded() bool {
+	if s.Volume != nil && s.Volume.IsEnded() && s.Volume.Value() <= 0 {
+		return true
+	}
+	switch ss := s.ss.(type) {
+	case nil:
+		return true
+	case sampling.Ender:
+		return ss.IsEnded()
+	case sampling.Lengther:
+		return s.pos.Pos < 0 || s.pos.Pos >= ss.Len()
+	}
+	return false
+}
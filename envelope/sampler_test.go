@@ -0,0 +1,206 @@
+package envelope
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// lengthStream is a constant mono stream of a fixed length
+type lengthStream int
+
+func (s lengthStream) GetSample(pos sampling.Pos) volume.Matrix {
+	if pos.Pos < 0 || pos.Pos >= int(s) {
+		return volume.Matrix{Channels: 1}
+	}
+	return volume.Matrix{StaticMatrix: volume.StaticMatrix{1}, Channels: 1}
+}
+
+func (s lengthStream) Len() int {
+	return int(s)
+}
+
+func TestSamplerEndsWithStream(t *testing.T) {
+	s := NewSampler(lengthStream(10), sampling.Pos{}, 1)
+	s.Volume = NewADSR(0, 0, 1, 0)
+	for i := 0; i < 10; i++ {
+		if s.IsEnded() {
+			t.Fatalf("sampler ended at %d, before the end of the stream", i)
+		}
+		s.Advance()
+	}
+	if !s.IsEnded() {
+		t.Error("sampler did not end at the end of the stream")
+	}
+}
+
+func TestSamplerEndsWithVolumeEnvelope(t *testing.T) {
+	s := NewSampler(lengthStream(1000), sampling.Pos{}, 1)
+	s.Volume = NewADSR(0, 0, 1, 4)
+	s.Advance()
+	s.Release()
+	for i := 0; i < 4; i++ {
+		if s.IsEnded() {
+			t.Fatalf("sampler ended %d steps into a 4 step release", i)
+		}
+		s.Advance()
+	}
+	if !s.IsEnded() {
+		t.Error("sampler did not end when the volume envelope finished")
+	}
+	if got := s.GetSample().StaticMatrix[0]; got != 0 {
+		t.Errorf("sample after the release: got %v, want 0", got)
+	}
+}
+
+func TestSamplerNilStream(t *testing.T) {
+	s := NewSampler(nil, sampling.Pos{}, 1)
+	if !s.IsEnded() {
+		t.Error("sampler with no stream is not ended")
+	}
+	if got := s.GetSample(); got.Channels != 0 {
+		t.Errorf("sample with no stream: got %v, want silence", got)
+	}
+}
+
+// constant is a generator with a fixed value that counts its steps
+type constant struct {
+	value float64
+	steps int
+}
+
+func (c *constant) Value() float64 { return c.value }
+func (c *constant) Advance()       { c.steps++ }
+func (c *constant) Release()       {}
+func (c *constant) IsEnded() bool  { return false }
+func (c *constant) Reset()         { c.steps = 0 }
+
+func TestSamplerTicks(t *testing.T) {
+	tests := []struct {
+		name           string
+		samplesPerStep int
+		advances       int
+		want           int
+	}{
+		{"every sample", 0, 10, 10},
+		{"one sample per step", 1, 10, 10},
+		{"first tick is full length", 4, 3, 0},
+		{"one tick", 4, 4, 1},
+		{"ticks", 4, 17, 4},
+		{"long ticks", 882, 882*3 - 1, 2},
+	}
+	for _, tt := range tests {
+		s := NewSampler(lengthStream(10000), sampling.Pos{}, 1)
+		g := &constant{value: 1}
+		s.Volume = g
+		s.SamplesPerStep = tt.samplesPerStep
+		for i := 0; i < tt.advances; i++ {
+			s.Advance()
+		}
+		if g.steps != tt.want {
+			t.Errorf("%s: got %d steps, want %d", tt.name, g.steps, tt.want)
+		}
+	}
+}
+
+func TestSamplerTickLengthChange(t *testing.T) {
+	s := NewSampler(lengthStream(10000), sampling.Pos{}, 1)
+	g := &constant{value: 1}
+	s.Volume = g
+	s.SamplesPerStep = 4
+	for i := 0; i < 6; i++ {
+		s.Advance()
+	}
+	if g.steps != 1 {
+		t.Fatalf("steps before the change: got %d, want 1", g.steps)
+	}
+
+	// a new tick length starts a full tick from where it changed
+	s.SamplesPerStep = 3
+	for i := 0; i < 2; i++ {
+		s.Advance()
+	}
+	if g.steps != 1 {
+		t.Errorf("steps part way into the new tick: got %d, want 1", g.steps)
+	}
+	s.Advance()
+	if g.steps != 2 {
+		t.Errorf("steps after the new tick: got %d, want 2", g.steps)
+	}
+}
+
+func TestSamplerPitch(t *testing.T) {
+	tests := []struct {
+		semitones float64
+		period    float32
+		want      sampling.Pos
+	}{
+		{0, 1, sampling.Pos{Pos: 8}},
+		{12, 1, sampling.Pos{Pos: 16}},
+		{-12, 1, sampling.Pos{Pos: 4}},
+		{-24, 0.5, sampling.Pos{Pos: 1}},
+		{12, 0.25, sampling.Pos{Pos: 4}},
+	}
+	for _, tt := range tests {
+		s := NewSampler(lengthStream(1000), sampling.Pos{}, tt.period)
+		s.Pitch = &constant{value: tt.semitones}
+		for i := 0; i < 8; i++ {
+			s.Advance()
+		}
+		if got := s.GetPosition(); got != tt.want {
+			t.Errorf("%v semitones at period %v: got %v, want %v", tt.semitones, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestSamplerPan(t *testing.T) {
+	tests := []struct {
+		name string
+		base panning.Position
+		pan  float64
+		want float32
+	}{
+		{"center", panning.CenterAhead, 0, 0.5},
+		{"full right", panning.CenterAhead, 1, 1},
+		{"half left", panning.CenterAhead, -0.5, 0.25},
+		{"narrowed from the left", panning.MakeStereoPosition(0.25, 0, 1), 1, 0.5},
+	}
+	for _, tt := range tests {
+		s := NewSampler(lengthStream(1000), sampling.Pos{}, 1)
+		s.Pan = &constant{value: tt.pan}
+		s.BasePan = tt.base
+		s.PanMixer = mixing.PanMixerStereo
+
+		got := s.GetSample()
+		want := mixing.PanMixerStereo.GetMixingMatrix(panning.MakeStereoPosition(tt.want, 0, 1))
+		if got.Channels != 2 {
+			t.Fatalf("%s: got %d channels, want 2", tt.name, got.Channels)
+		}
+		for c := 0; c < 2; c++ {
+			if math.Abs(float64(got.StaticMatrix[c]-want.StaticMatrix[c])) > 1e-6 {
+				t.Errorf("%s channel %d: got %v, want %v", tt.name, c, got.StaticMatrix[c], want.StaticMatrix[c])
+			}
+		}
+
+		// the samples are already panned, so they're mixed in at unity
+		mtx := s.VolMatrix(mixing.PanMixerStereo, panning.MakeStereoPosition(0, 0, 1))
+		if mtx.Channels != 2 || mtx.StaticMatrix[0] != 1 || mtx.StaticMatrix[1] != 1 {
+			t.Errorf("%s mix matrix: got %v, want unity", tt.name, mtx)
+		}
+	}
+
+	// without a panning envelope, the sampler is mixed like any other stream
+	s := NewSampler(lengthStream(1000), sampling.Pos{}, 1)
+	s.PanMixer = mixing.PanMixerStereo
+	pan := panning.MakeStereoPosition(0.2, 0, 1)
+	if got, want := s.VolMatrix(mixing.PanMixerStereo, pan), mixing.PanMixerStereo.GetMixingMatrix(pan); got != want {
+		t.Errorf("mix matrix without a panning envelope: got %v, want %v", got, want)
+	}
+	if got := s.GetSample(); got.Channels != 1 {
+		t.Errorf("sample without a panning envelope: got %d channels, want 1", got.Channels)
+	}
+}
@@ -0,0 +1,89 @@
+package fft
+
+import (
+	"math"
+	"math/bits"
+)
+
+// Plan is a precomputed radix-2 fast Fourier transform of a fixed power-of-two size
+type Plan struct {
+	n        int
+	twiddles []complex128
+	rev      []int
+}
+
+// NewPlan returns a transform plan for `n` points. It panics if `n` is not a power of two
+func NewPlan(n int) *Plan {
+	if n <= 0 || n&(n-1) != 0 {
+		panic("fft size must be a power of two")
+	}
+	p := &Plan{
+		n:        n,
+		twiddles: make([]complex128, n/2),
+		rev:      make([]int, n),
+	}
+	for i := range p.twiddles {
+		s, c := math.Sincos(-2 * math.Pi * float64(i) / float64(n))
+		p.twiddles[i] = complex(c, s)
+	}
+	shift := uint(bits.UintSize - bits.TrailingZeros(uint(n)))
+	for i := range p.rev {
+		if n > 1 {
+			p.rev[i] = int(bits.Reverse(uint(i)) >> shift)
+		}
+	}
+	return p
+}
+
+// Len returns the number of points of the transform
+func (p *Plan) Len() int {
+	return p.n
+}
+
+// Forward computes the forward transform of `data` in place
+func (p *Plan) Forward(data []complex128) {
+	p.transform(data, false)
+}
+
+// Inverse computes the inverse transform of `data` in place, including the 1/n scaling
+func (p *Plan) Inverse(data []complex128) {
+	p.transform(data, true)
+	scale := complex(1/float64(p.n), 0)
+	for i := range data[:p.n] {
+		data[i] *= scale
+	}
+}
+
+func (p *Plan) transform(data []complex128, inverse bool) {
+	n := p.n
+	data = data[:n]
+	for i, j := range p.rev {
+		if i < j {
+			data[i], data[j] = data[j], data[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		half := size >> 1
+		step := n / size
+		for start := 0; start < n; start += size {
+			for k := 0; k < half; k++ {
+				w := p.twiddles[k*step]
+				if inverse {
+					w = complex(real(w), -imag(w))
+				}
+				a := data[start+k]
+				b := data[start+k+half] * w
+				data[start+k] = a + b
+				data[start+k+half] = a - b
+			}
+		}
+	}
+}
+
+// NextPowerOfTwo returns the smallest power of two that is at least `n`
+func NextPowerOfTwo(n int) int {
+	if n <= 1 {
+		return 1
+	}
+	return 1 << uint(bits.Len(uint(n-1)))
+}
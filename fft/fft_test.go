@@ -0,0 +1,159 @@
+package fft
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+)
+
+// testSizes are the transform sizes checked against the naive DFT
+var testSizes = []int{2, 4, 8, 16, 32, 64, 128, 256, 512, 1024, 2048, 4096}
+
+// naiveDFT is the O(n²) reference transform. The angle is reduced to an exact index before
+// computing the twiddle, so its error doesn't grow with the size
+func naiveDFT(x []complex128, inverse bool) []complex128 {
+	n := len(x)
+	sign := -1.0
+	if inverse {
+		sign = 1
+	}
+	out := make([]complex128, n)
+	for k := range out {
+		var sum complex128
+		for j, v := range x {
+			s, c := math.Sincos(sign * 2 * math.Pi * float64((k*j)%n) / float64(n))
+			sum += v * complex(c, s)
+		}
+		if inverse {
+			sum /= complex(float64(n), 0)
+		}
+		out[k] = sum
+	}
+	return out
+}
+
+func randomComplex(rng *rand.Rand, n int) []complex128 {
+	x := make([]complex128, n)
+	for i := range x {
+		x[i] = complex(rng.Float64()*2-1, rng.Float64()*2-1)
+	}
+	return x
+}
+
+// maxError returns the largest difference between two transforms, relative to the largest magnitude of `want`
+func maxError(got []complex128, want []complex128) float64 {
+	var scale, worst float64
+	for i := range want {
+		scale = math.Max(scale, cmplx.Abs(want[i]))
+		worst = math.Max(worst, cmplx.Abs(got[i]-want[i]))
+	}
+	if scale == 0 {
+		return worst
+	}
+	return worst / scale
+}
+
+func TestPlanMatchesDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range append([]int{1}, testSizes...) {
+		p := NewPlan(n)
+		if p.Len() != n {
+			t.Fatalf("size %d: Len() = %d", n, p.Len())
+		}
+		x := randomComplex(rng, n)
+
+		got := append([]complex128(nil), x...)
+		p.Forward(got)
+		if err := maxError(got, naiveDFT(x, false)); err > 1e-12 {
+			t.Errorf("size %d: forward error %v", n, err)
+		}
+
+		got = append([]complex128(nil), x...)
+		p.Inverse(got)
+		if err := maxError(got, naiveDFT(x, true)); err > 1e-12 {
+			t.Errorf("size %d: inverse error %v", n, err)
+		}
+	}
+}
+
+func TestPlanRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	for _, n := range testSizes {
+		p := NewPlan(n)
+		x := randomComplex(rng, n)
+		got := append([]complex128(nil), x...)
+		p.Forward(got)
+		p.Inverse(got)
+		if err := maxError(got, x); err > 1e-12 {
+			t.Errorf("size %d: round trip error %v", n, err)
+		}
+	}
+}
+
+func TestPlanIgnoresExtraData(t *testing.T) {
+	p := NewPlan(8)
+	data := make([]complex128, 10)
+	data[0] = 1
+	data[8], data[9] = 5, 6
+	p.Forward(data)
+	for i := 0; i < 8; i++ {
+		if data[i] != 1 {
+			t.Errorf("bin %d: got %v, want 1", i, data[i])
+		}
+	}
+	if data[8] != 5 || data[9] != 6 {
+		t.Errorf("data past the transform size was changed: %v", data[8:])
+	}
+}
+
+func TestNewPlanPanics(t *testing.T) {
+	for _, n := range []int{0, -4, 3, 12, 1000} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewPlan(%d) did not panic", n)
+				}
+			}()
+			NewPlan(n)
+		}()
+	}
+}
+
+func TestNextPowerOfTwo(t *testing.T) {
+	tests := []struct{ n, want int }{
+		{-1, 1}, {0, 1}, {1, 1}, {2, 2}, {3, 4}, {4, 4}, {5, 8}, {1000, 1024}, {1024, 1024}, {1025, 2048},
+	}
+	for _, tt := range tests {
+		if got := NextPowerOfTwo(tt.n); got != tt.want {
+			t.Errorf("NextPowerOfTwo(%d) = %d, want %d", tt.n, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkPlanForward(b *testing.B) {
+	for _, n := range []int{256, 1024, 4096} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			p := NewPlan(n)
+			data := randomComplex(rand.New(rand.NewSource(1)), n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Forward(data)
+			}
+		})
+	}
+}
+
+func BenchmarkPlanInverse(b *testing.B) {
+	for _, n := range []int{256, 1024, 4096} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			p := NewPlan(n)
+			data := randomComplex(rand.New(rand.NewSource(1)), n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Inverse(data)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package fft
+
+import "math"
+
+// RealPlan is a precomputed fast Fourier transform of real-valued data of a fixed power-of-two size.
+// It packs the data into a complex transform of half the size, so it does about half the work of
+// transforming the real data with a Plan.
+type RealPlan struct {
+	n        int
+	half     *Plan
+	twiddles []complex128
+	scratch  []complex128
+}
+
+// NewRealPlan returns a real transform plan for `n` points.
+// It panics if `n` is not a power of two of at least 2
+func NewRealPlan(n int) *RealPlan {
+	if n < 2 || n&(n-1) != 0 {
+		panic("real fft size must be a power of two of at least 2")
+	}
+	p := &RealPlan{
+		n:        n,
+		half:     NewPlan(n / 2),
+		twiddles: make([]complex128, n/2),
+		scratch:  make([]complex128, n/2),
+	}
+	for k := range p.twiddles {
+		s, c := math.Sincos(-2 * math.Pi * float64(k) / float64(n))
+		p.twiddles[k] = complex(c, s)
+	}
+	return p
+}
+
+// Len returns the number of real points of the transform
+func (p *RealPlan) Len() int {
+	return p.n
+}
+
+// Bins returns the number of frequency bins produced by the transform, n/2+1
+func (p *RealPlan) Bins() int {
+	return p.n/2 + 1
+}
+
+// Forward computes the transform of the `n` real values of `in`, writing the n/2+1
+// non-negative frequency bins to `out`
+func (p *RealPlan) Forward(in []float64, out []complex128) {
+	h := p.n / 2
+	z := p.scratch
+	for k := range z {
+		z[k] = complex(in[2*k], in[2*k+1])
+	}
+	p.half.Forward(z)
+
+	for k := 0; k <= h; k++ {
+		a := z[k%h]
+		b := conj(z[(h-k)%h])
+		even := (a + b) / 2
+		odd := (a - b) / complex(0, 2)
+		out[k] = even + p.twiddle(k)*odd
+	}
+}
+
+// Inverse computes the `n` real values from the n/2+1 frequency bins of `in`, including the 1/n scaling
+func (p *RealPlan) Inverse(in []complex128, out []float64) {
+	h := p.n / 2
+	z := p.scratch
+	for k := 0; k < h; k++ {
+		a := in[k]
+		b := conj(in[h-k])
+		even := (a + b) / 2
+		odd := (a - b) / 2 * conj(p.twiddle(k))
+		z[k] = even + complex(0, 1)*odd
+	}
+	p.half.Inverse(z)
+	for k, v := range z {
+		out[2*k] = real(v)
+		out[2*k+1] = imag(v)
+	}
+}
+
+// twiddle returns e^(-2πik/n) for 0 <= k <= n/2
+func (p *RealPlan) twiddle(k int) complex128 {
+	if k == p.n/2 {
+		return -1
+	}
+	return p.twiddles[k]
+}
+
+func conj(v complex128) complex128 {
+	return complex(real(v), -imag(v))
+}
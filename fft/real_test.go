@@ -0,0 +1,126 @@
+package fft
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func randomReal(rng *rand.Rand, n int) []float64 {
+	x := make([]float64, n)
+	for i := range x {
+		x[i] = rng.Float64()*2 - 1
+	}
+	return x
+}
+
+func toComplex(x []float64) []complex128 {
+	c := make([]complex128, len(x))
+	for i, v := range x {
+		c[i] = complex(v, 0)
+	}
+	return c
+}
+
+func TestRealPlanMatchesDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	for _, n := range testSizes {
+		p := NewRealPlan(n)
+		if p.Len() != n || p.Bins() != n/2+1 {
+			t.Fatalf("size %d: Len() = %d, Bins() = %d", n, p.Len(), p.Bins())
+		}
+		x := randomReal(rng, n)
+
+		got := make([]complex128, p.Bins())
+		p.Forward(x, got)
+		want := naiveDFT(toComplex(x), false)[:p.Bins()]
+		if err := maxError(got, want); err > 1e-12 {
+			t.Errorf("size %d: forward error %v", n, err)
+		}
+	}
+}
+
+func TestRealPlanInverseMatchesDFT(t *testing.T) {
+	rng := rand.New(rand.NewSource(4))
+	for _, n := range testSizes {
+		p := NewRealPlan(n)
+		// build the spectrum of a real signal: real DC and Nyquist bins, conjugate-symmetric elsewhere
+		full := make([]complex128, n)
+		full[0] = complex(rng.Float64()*2-1, 0)
+		full[n/2] = complex(rng.Float64()*2-1, 0)
+		for k := 1; k < n/2; k++ {
+			full[k] = complex(rng.Float64()*2-1, rng.Float64()*2-1)
+			full[n-k] = conj(full[k])
+		}
+		want := naiveDFT(full, true)
+
+		got := make([]float64, n)
+		p.Inverse(full[:p.Bins()], got)
+		if err := maxError(toComplex(got), want); err > 1e-12 {
+			t.Errorf("size %d: inverse error %v", n, err)
+		}
+		for i, v := range want {
+			if math.Abs(imag(v)) > 1e-12 {
+				t.Fatalf("size %d: reference sample %d is not real: %v", n, i, v)
+			}
+		}
+	}
+}
+
+func TestRealPlanRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	for _, n := range testSizes {
+		p := NewRealPlan(n)
+		x := randomReal(rng, n)
+		spectrum := make([]complex128, p.Bins())
+		got := make([]float64, n)
+		p.Forward(x, spectrum)
+		p.Inverse(spectrum, got)
+		if err := maxError(toComplex(got), toComplex(x)); err > 1e-12 {
+			t.Errorf("size %d: round trip error %v", n, err)
+		}
+	}
+}
+
+func TestNewRealPlanPanics(t *testing.T) {
+	for _, n := range []int{0, 1, 3, 12} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewRealPlan(%d) did not panic", n)
+				}
+			}()
+			NewRealPlan(n)
+		}()
+	}
+}
+
+func BenchmarkRealPlanForward(b *testing.B) {
+	for _, n := range []int{256, 1024, 4096} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			p := NewRealPlan(n)
+			in := randomReal(rand.New(rand.NewSource(1)), n)
+			out := make([]complex128, p.Bins())
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Forward(in, out)
+			}
+		})
+	}
+}
+
+func BenchmarkRealPlanInverse(b *testing.B) {
+	for _, n := range []int{256, 1024, 4096} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			p := NewRealPlan(n)
+			in := make([]complex128, p.Bins())
+			p.Forward(randomReal(rand.New(rand.NewSource(1)), n), in)
+			out := make([]float64, n)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Inverse(in, out)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package fft
+
+import "math"
+
+// Window is a window function applied to a block of samples before transforming it
+type Window int
+
+const (
+	// WindowRectangular leaves the block unchanged
+	WindowRectangular = Window(iota)
+	// WindowHann is a raised cosine window, a good general-purpose choice
+	WindowHann
+	// WindowBlackmanHarris is a 4-term Blackman-Harris window with very low side lobes
+	WindowBlackmanHarris
+	// WindowFlatTop is a flat-top window that measures the amplitude of sinusoids accurately
+	WindowFlatTop
+)
+
+// Coefficients returns the `n` coefficients of the window.
+// The windows are periodic, as is usual for spectral analysis
+func (w Window) Coefficients(n int) []float64 {
+	var terms []float64
+	switch w {
+	case WindowHann:
+		terms = []float64{0.5, 0.5}
+	case WindowBlackmanHarris:
+		terms = []float64{0.35875, 0.48829, 0.14128, 0.01168}
+	case WindowFlatTop:
+		terms = []float64{0.21557895, 0.41663158, 0.277263158, 0.083578947, 0.006947368}
+	default:
+		terms = []float64{1}
+	}
+
+	coeffs := make([]float64, n)
+	for i := range coeffs {
+		x := 2 * math.Pi * float64(i) / float64(n)
+		sign := 1.0
+		var v float64
+		for k, a := range terms {
+			v += sign * a * math.Cos(float64(k)*x)
+			sign = -sign
+		}
+		coeffs[i] = v
+	}
+	return coeffs
+}
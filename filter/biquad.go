@@ -0,0 +1,234 @@
+package filter
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+const (
+	// minCutoff is the lowest cutoff frequency in Hz the coefficient calculations will allow
+	minCutoff = 1.0
+	// maxCutoffRatio is the highest cutoff frequency allowed, as a fraction of the sample rate
+	maxCutoffRatio = 0.49
+	// minQ is the lowest Q the coefficient calculations will allow
+	minQ = 0.01
+)
+
+// BiquadCoefficients are the normalized coefficients of a second-order IIR filter
+type BiquadCoefficients struct {
+	B0, B1, B2 float64
+	A1, A2     float64
+}
+
+// Biquad is a second-order IIR filter, applied independently to each channel
+type Biquad struct {
+	Coefficients BiquadCoefficients
+
+	z1, z2 [volume.MaxChannels]float64
+}
+
+// Filter processes a single multichannel sample
+func (f *Biquad) Filter(dry volume.Matrix) volume.Matrix {
+	k := &f.Coefficients
+	wet := dry
+	for c := 0; c < dry.Channels; c++ {
+		// transposed direct form II
+		x := float64(dry.StaticMatrix[c])
+		y := k.B0*x + f.z1[c]
+		f.z1[c] = k.B1*x - k.A1*y + f.z2[c]
+		f.z2[c] = k.B2*x - k.A2*y
+		wet.StaticMatrix[c] = volume.Volume(y)
+	}
+	return wet
+}
+
+// Reset clears the filter history
+func (f *Biquad) Reset() {
+	for c := range f.z1 {
+		f.z1[c] = 0
+		f.z2[c] = 0
+	}
+}
+
+// clampCutoff keeps a cutoff frequency within the range that produces a stable filter
+func clampCutoff(sampleRate float64, cutoff float64) float64 {
+	if limit := sampleRate * maxCutoffRatio; cutoff > limit {
+		cutoff = limit
+	}
+	if cutoff < minCutoff {
+		cutoff = minCutoff
+	}
+	return cutoff
+}
+
+func clampQ(q float64) float64 {
+	if q < minQ || math.IsNaN(q) {
+		return minQ
+	}
+	return q
+}
+
+// normalize divides the coefficients by a0
+func normalize(b0, b1, b2, a0, a1, a2 float64) BiquadCoefficients {
+	return BiquadCoefficients{
+		B0: b0 / a0,
+		B1: b1 / a0,
+		B2: b2 / a0,
+		A1: a1 / a0,
+		A2: a2 / a0,
+	}
+}
+
+// LowPassCoefficients returns the coefficients of a resonant low-pass filter
+func LowPassCoefficients(sampleRate float64, cutoff float64, q float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, cutoff) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	return normalize((1-cw)/2, 1-cw, (1-cw)/2, 1+alpha, -2*cw, 1-alpha)
+}
+
+// HighPassCoefficients returns the coefficients of a resonant high-pass filter
+func HighPassCoefficients(sampleRate float64, cutoff float64, q float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, cutoff) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	return normalize((1+cw)/2, -(1 + cw), (1+cw)/2, 1+alpha, -2*cw, 1-alpha)
+}
+
+// ResonantBiquad is a resonant low- or high-pass biquad filter with smoothed cutoff and resonance
+type ResonantBiquad struct {
+	Mode       Mode
+	SampleRate float64
+	// Cutoff is the cutoff frequency in Hz
+	Cutoff float64
+	// Resonance is the Q of the filter. 0.7071 is the flattest response without a resonant peak
+	Resonance float64
+
+	biquad    Biquad
+	cutoff    smoother
+	resonance smoother
+}
+
+// NewResonantBiquad returns a resonant biquad filter that smooths parameter changes over `smoothing` samples
+func NewResonantBiquad(mode Mode, sampleRate float64, cutoff float64, resonance float64, smoothing float64) *ResonantBiquad {
+	f := &ResonantBiquad{
+		Mode:       mode,
+		SampleRate: sampleRate,
+		Cutoff:     cutoff,
+		Resonance:  resonance,
+	}
+	f.SetSmoothing(smoothing)
+	return f
+}
+
+// SetSmoothing sets the time constant, in samples, over which parameter changes are smoothed
+func (f *ResonantBiquad) SetSmoothing(samples float64) {
+	f.cutoff.setTime(samples)
+	f.resonance.setTime(samples)
+}
+
+// Filter processes a single multichannel sample
+func (f *ResonantBiquad) Filter(dry volume.Matrix) volume.Matrix {
+	cutoff, cutoffChanged := f.cutoff.next(f.Cutoff)
+	resonance, resonanceChanged := f.resonance.next(f.Resonance)
+	if cutoffChanged || resonanceChanged {
+		switch f.Mode {
+		case ModeHighPass:
+			f.biquad.Coefficients = HighPassCoefficients(f.SampleRate, cutoff, resonance)
+		default:
+			f.biquad.Coefficients = LowPassCoefficients(f.SampleRate, cutoff, resonance)
+		}
+	}
+	return f.biquad.Filter(dry)
+}
+
+// Reset clears the filter history and jumps the parameters to their current values
+func (f *ResonantBiquad) Reset() {
+	f.biquad.Reset()
+	f.cutoff.reset()
+	f.resonance.reset()
+}
+
+// BandPassCoefficients returns the coefficients of a band-pass filter with a constant 0dB peak gain
+func BandPassCoefficients(sampleRate float64, center float64, q float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, center) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	return normalize(alpha, 0, -alpha, 1+alpha, -2*cw, 1-alpha)
+}
+
+// NotchCoefficients returns the coefficients of a notch filter
+func NotchCoefficients(sampleRate float64, center float64, q float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, center) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	return normalize(1, -2*cw, 1, 1+alpha, -2*cw, 1-alpha)
+}
+
+// AllPassCoefficients returns the coefficients of an all-pass filter
+func AllPassCoefficients(sampleRate float64, center float64, q float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, center) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	return normalize(1-alpha, -2*cw, 1+alpha, 1+alpha, -2*cw, 1-alpha)
+}
+
+// PeakingCoefficients returns the coefficients of a peaking EQ filter with a gain in dB
+func PeakingCoefficients(sampleRate float64, center float64, q float64, gainDB float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, center) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	a := math.Pow(10, gainDB/40)
+	return normalize(1+alpha*a, -2*cw, 1-alpha*a, 1+alpha/a, -2*cw, 1-alpha/a)
+}
+
+// LowShelfCoefficients returns the coefficients of a low shelf filter with a gain in dB
+func LowShelfCoefficients(sampleRate float64, corner float64, q float64, gainDB float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, corner) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	a := math.Pow(10, gainDB/40)
+	sa := 2 * math.Sqrt(a) * alpha
+	return normalize(
+		a*((a+1)-(a-1)*cw+sa),
+		2*a*((a-1)-(a+1)*cw),
+		a*((a+1)-(a-1)*cw-sa),
+		(a+1)+(a-1)*cw+sa,
+		-2*((a-1)+(a+1)*cw),
+		(a+1)+(a-1)*cw-sa,
+	)
+}
+
+// HighShelfCoefficients returns the coefficients of a high shelf filter with a gain in dB
+func HighShelfCoefficients(sampleRate float64, corner float64, q float64, gainDB float64) BiquadCoefficients {
+	w0 := 2 * math.Pi * clampCutoff(sampleRate, corner) / sampleRate
+	sw, cw := math.Sincos(w0)
+	alpha := sw / (2 * clampQ(q))
+	a := math.Pow(10, gainDB/40)
+	sa := 2 * math.Sqrt(a) * alpha
+	return normalize(
+		a*((a+1)+(a-1)*cw+sa),
+		-2*a*((a-1)+(a+1)*cw),
+		a*((a+1)+(a-1)*cw-sa),
+		(a+1)-(a-1)*cw+sa,
+		2*((a-1)-(a+1)*cw),
+		(a+1)-(a-1)*cw-sa,
+	)
+}
+
+// Response returns the complex frequency response of the filter at a frequency in Hz
+func (k BiquadCoefficients) Response(sampleRate float64, freq float64) complex128 {
+	w := 2 * math.Pi * freq / sampleRate
+	z1 := cmplx.Exp(complex(0, -w))
+	z2 := z1 * z1
+	num := complex(k.B0, 0) + complex(k.B1, 0)*z1 + complex(k.B2, 0)*z2
+	den := complex(1, 0) + complex(k.A1, 0)*z1 + complex(k.A2, 0)*z2
+	return num / den
+}
+
+// MagnitudeDB returns the gain of the filter in dB at a frequency in Hz
+func (k BiquadCoefficients) MagnitudeDB(sampleRate float64, freq float64) float64 {
+	return volume.ToDB(cmplx.Abs(k.Response(sampleRate, freq)))
+}
@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+// BandType is the response type of an EQ band
+type BandType uint8
+
+const (
+	// BandPeaking boosts or cuts around a center frequency
+	BandPeaking = BandType(iota)
+	// BandLowShelf boosts or cuts below a corner frequency
+	BandLowShelf
+	// BandHighShelf boosts or cuts above a corner frequency
+	BandHighShelf
+	// BandLowPass passes frequencies below the cutoff
+	BandLowPass
+	// BandHighPass passes frequencies above the cutoff
+	BandHighPass
+	// BandBandPass passes frequencies around the center frequency
+	BandBandPass
+	// BandNotch removes frequencies around the center frequency
+	BandNotch
+	// BandAllPass shifts the phase around the center frequency without changing the gain
+	BandAllPass
+)
+
+// Coefficients returns the biquad coefficients for a band of this type
+func (t BandType) Coefficients(sampleRate float64, freq float64, q float64, gainDB float64) BiquadCoefficients {
+	switch t {
+	case BandLowShelf:
+		return LowShelfCoefficients(sampleRate, freq, q, gainDB)
+	case BandHighShelf:
+		return HighShelfCoefficients(sampleRate, freq, q, gainDB)
+	case BandLowPass:
+		return LowPassCoefficients(sampleRate, freq, q)
+	case BandHighPass:
+		return HighPassCoefficients(sampleRate, freq, q)
+	case BandBandPass:
+		return BandPassCoefficients(sampleRate, freq, q)
+	case BandNotch:
+		return NotchCoefficients(sampleRate, freq, q)
+	case BandAllPass:
+		return AllPassCoefficients(sampleRate, freq, q)
+	default:
+		return PeakingCoefficients(sampleRate, freq, q, gainDB)
+	}
+}
+
+// Band is a single biquad EQ band whose parameters are smoothed to avoid zipper noise
+type Band struct {
+	Type BandType
+	// Frequency is the center, corner or cutoff frequency in Hz
+	Frequency float64
+	Q         float64
+	// Gain is the boost or cut in dB, used by the peaking and shelf types
+	Gain float64
+
+	biquad   Biquad
+	freq     smoother
+	q        smoother
+	gain     smoother
+	lastType BandType
+	primed   bool
+}
+
+// Coefficients returns the coefficients for the band's target parameters
+func (b *Band) Coefficients(sampleRate float64) BiquadCoefficients {
+	return b.Type.Coefficients(sampleRate, b.Frequency, b.Q, b.Gain)
+}
+
+func (b *Band) setSmoothing(samples float64) {
+	b.freq.setTime(samples)
+	b.q.setTime(samples)
+	b.gain.setTime(samples)
+}
+
+func (b *Band) filter(sampleRate float64, dry volume.Matrix) volume.Matrix {
+	// frequency is smoothed in the log domain so sweeps sound even
+	logFreq, freqChanged := b.freq.next(math.Log(math.Max(b.Frequency, minCutoff)))
+	q, qChanged := b.q.next(b.Q)
+	gain, gainChanged := b.gain.next(b.Gain)
+	if freqChanged || qChanged || gainChanged || !b.primed || b.lastType != b.Type {
+		b.biquad.Coefficients = b.Type.Coefficients(sampleRate, math.Exp(logFreq), q, gain)
+		b.lastType = b.Type
+		b.primed = true
+	}
+	return b.biquad.Filter(dry)
+}
+
+func (b *Band) reset() {
+	b.biquad.Reset()
+	b.freq.reset()
+	b.q.reset()
+	b.gain.reset()
+	b.primed = false
+}
+
+// EQ is a cascade of biquad bands applied to every channel
+type EQ struct {
+	SampleRate float64
+	Bands      []*Band
+
+	smoothing float64
+}
+
+// NewEQ returns an empty EQ that smooths parameter changes over `smoothing` samples
+func NewEQ(sampleRate float64, smoothing float64) *EQ {
+	return &EQ{
+		SampleRate: sampleRate,
+		smoothing:  smoothing,
+	}
+}
+
+// AddBand appends a band to the EQ and returns it
+func (e *EQ) AddBand(t BandType, freq float64, q float64, gainDB float64) *Band {
+	b := &Band{
+		Type:      t,
+		Frequency: freq,
+		Q:         q,
+		Gain:      gainDB,
+	}
+	b.setSmoothing(e.smoothing)
+	e.Bands = append(e.Bands, b)
+	return b
+}
+
+// Filter processes a single multichannel sample through every band
+func (e *EQ) Filter(dry volume.Matrix) volume.Matrix {
+	wet := dry
+	for _, b := range e.Bands {
+		wet = b.filter(e.SampleRate, wet)
+	}
+	return wet
+}
+
+// Reset clears the history of every band
+func (e *EQ) Reset() {
+	for _, b := range e.Bands {
+		b.reset()
+	}
+}
+
+// Response returns the combined gain in dB of all the bands' target parameters at a frequency in Hz
+func (e *EQ) Response(freq float64) float64 {
+	var db float64
+	for _, b := range e.Bands {
+		db += b.Coefficients(e.SampleRate).MagnitudeDB(e.SampleRate, freq)
+	}
+	return db
+}
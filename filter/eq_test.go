@@ -0,0 +1,92 @@
+package filter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEQBandGain(t *testing.T) {
+	const sampleRate = 48000
+	tests := []struct {
+		name    string
+		band    BandType
+		freq    float64
+		q       float64
+		gain    float64
+		measure float64
+		wantDB  float64
+	}{
+		{"peaking boost at center", BandPeaking, 1000, 1, 6, 1000, 6},
+		{"peaking cut at center", BandPeaking, 1000, 2, -9, 1000, -9},
+		{"peaking far from center", BandPeaking, 1000, 2, 12, 20, 0},
+		{"low shelf below corner", BandLowShelf, 1000, 0.7071, 6, 40, 6},
+		{"low shelf above corner", BandLowShelf, 1000, 0.7071, 6, 15000, 0},
+		{"high shelf above corner", BandHighShelf, 1000, 0.7071, -6, 15000, -6},
+		{"low-pass at cutoff", BandLowPass, 1000, 1 / math.Sqrt2, 0, 1000, -10 * math.Log10(2)},
+		{"high-pass at cutoff", BandHighPass, 1000, 1 / math.Sqrt2, 0, 1000, -10 * math.Log10(2)},
+		{"band-pass at center", BandBandPass, 1000, 4, 0, 1000, 0},
+		{"all-pass at center", BandAllPass, 1000, 4, 0, 1000, 0},
+	}
+	for _, tt := range tests {
+		e := NewEQ(sampleRate, 0)
+		e.AddBand(tt.band, tt.freq, tt.q, tt.gain)
+		if got := e.Response(tt.measure); math.Abs(got-tt.wantDB) > 0.05 {
+			t.Errorf("%s: Response got %.3f dB, want %.3f dB", tt.name, got, tt.wantDB)
+		}
+		if got := 20 * math.Log10(sineGain(e.Filter, sampleRate, tt.measure, 0.25)); math.Abs(got-tt.wantDB) > 0.05 {
+			t.Errorf("%s: measured %.3f dB, want %.3f dB", tt.name, got, tt.wantDB)
+		}
+	}
+
+	e := NewEQ(sampleRate, 0)
+	e.AddBand(BandNotch, 1000, 4, 0)
+	if got := e.Response(1000); got > -60 {
+		t.Errorf("notch at center: got %.3f dB, want below -60 dB", got)
+	}
+}
+
+func TestEQBandsCascade(t *testing.T) {
+	e := NewEQ(48000, 0)
+	e.AddBand(BandPeaking, 1000, 1, 6)
+	e.AddBand(BandPeaking, 1000, 1, 3)
+	if got := 20 * math.Log10(sineGain(e.Filter, 48000, 1000, 0.1)); math.Abs(got-9) > 0.05 {
+		t.Errorf("two peaking bands at the same center: got %.3f dB, want 9 dB", got)
+	}
+}
+
+func TestEQSmoothing(t *testing.T) {
+	const smoothing = 100
+	e := NewEQ(48000, smoothing)
+	b := e.AddBand(BandPeaking, 100, 1, 0)
+	e.Filter(mono(0))
+
+	b.Gain = 12
+	b.Frequency = 10000
+	for i := 0; i < smoothing; i++ {
+		e.Filter(mono(0))
+	}
+	// a one-pole smoother covers 1-1/e of the way in one time constant
+	frac := 1 - math.Exp(-1)
+	if got, want := b.gain.value, 12*frac; math.Abs(got-want) > 1e-6 {
+		t.Errorf("gain after one time constant: got %v, want %v", got, want)
+	}
+	// frequency moves evenly in octaves rather than in Hz
+	if got, want := math.Exp(b.freq.value), 100*math.Pow(100, frac); math.Abs(got-want) > want*1e-6 {
+		t.Errorf("frequency after one time constant: got %v, want %v", got, want)
+	}
+
+	for i := 0; i < smoothing*20; i++ {
+		e.Filter(mono(0))
+	}
+	if b.gain.value != 12 || b.freq.value != math.Log(10000) {
+		t.Errorf("settled parameters: got %v dB at %v Hz, want 12 dB at 10000 Hz", b.gain.value, math.Exp(b.freq.value))
+	}
+
+	// a reset jumps straight to the target
+	b.Gain = -6
+	e.Reset()
+	e.Filter(mono(0))
+	if b.gain.value != -6 {
+		t.Errorf("gain after reset: got %v, want -6", b.gain.value)
+	}
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Filter is a per-sample audio filter
+type Filter interface {
+	Filter(dry volume.Matrix) volume.Matrix
+	Reset()
+}
+
+// Mode is the response type of a resonant filter
+type Mode uint8
+
+const (
+	// ModeLowPass passes frequencies below the cutoff
+	ModeLowPass = Mode(iota)
+	// ModeHighPass passes frequencies above the cutoff
+	ModeHighPass
+)
+
+// smoother is a one-pole parameter smoother
+type smoother struct {
+	value  float64
+	target float64
+	coeff  float64
+	primed bool
+}
+
+// setTime sets the smoothing time constant in samples. Zero disables smoothing
+func (s *smoother) setTime(samples float64) {
+	if samples <= 0 {
+		s.coeff = 1
+		return
+	}
+	s.coeff = 1 - math.Exp(-1/samples)
+}
+
+// next moves the value toward the target and returns it, along with whether it changed
+func (s *smoother) next(target float64) (float64, bool) {
+	if !s.primed {
+		s.primed = true
+		s.value = target
+		s.target = target
+		return s.value, true
+	}
+	s.target = target
+	if s.value == target {
+		return s.value, false
+	}
+	if s.coeff >= 1 {
+		s.value = target
+		return s.value, true
+	}
+	s.value += (target - s.value) * s.coeff
+	if math.Abs(target-s.value) < 1e-6*math.Max(1, math.Abs(target)) {
+		s.value = target
+	}
+	return s.value, true
+}
+
+// reset makes the next value jump straight to the target
+func (s *smoother) reset() {
+	s.primed = false
+}
@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+const (
+	// ITMaxCutoff is the highest Impulse Tracker filter cutoff value
+	ITMaxCutoff = 127
+	// ITMaxResonance is the highest Impulse Tracker filter resonance value
+	ITMaxResonance = 127
+
+	// itFilterStateLimit is the value the filter history is clamped to, matching the
+	// saturation of the original mixer so that extreme resonance can't blow up
+	itFilterStateLimit = 2.0
+
+	// itMaxCutoffRatio is the highest cutoff frequency as a fraction of the sample rate.
+	// Near the Nyquist frequency the filter design puts a pole outside the unit circle
+	// at high resonance, which only happens at low mixing rates
+	itMaxCutoffRatio = 0.3
+)
+
+// ITCutoffFrequency returns the cutoff frequency in Hz of an Impulse Tracker filter cutoff value (0-127).
+// `extendedRange` selects the wider range used by ModPlug/OpenMPT extended filter range songs
+func ITCutoffFrequency(cutoff float64, extendedRange bool) float64 {
+	div := 24.0
+	if extendedRange {
+		div = 20.0
+	}
+	return 110.0 * math.Pow(2.0, 0.25+cutoff/div)
+}
+
+// ITFilter is a two-pole resonant filter matching the response of the Impulse Tracker mixer.
+// Cutoff and resonance use the tracker's 0-127 range and are smoothed per sample.
+type ITFilter struct {
+	Mode       Mode
+	SampleRate float64
+	// Cutoff is the filter cutoff (0-127)
+	Cutoff float64
+	// Resonance is the filter resonance (0-127)
+	Resonance float64
+	// ExtendedRange uses the ModPlug/OpenMPT extended cutoff range
+	ExtendedRange bool
+
+	a0, b0, b1 float64
+	y1, y2     [volume.MaxChannels]float64
+	cutoff     smoother
+	resonance  smoother
+}
+
+// NewITFilter returns an Impulse Tracker style filter that smooths parameter changes over `smoothing` samples
+func NewITFilter(mode Mode, sampleRate float64, cutoff float64, resonance float64, smoothing float64) *ITFilter {
+	f := &ITFilter{
+		Mode:       mode,
+		SampleRate: sampleRate,
+		Cutoff:     cutoff,
+		Resonance:  resonance,
+	}
+	f.SetSmoothing(smoothing)
+	return f
+}
+
+// SetSmoothing sets the time constant, in samples, over which parameter changes are smoothed
+func (f *ITFilter) SetSmoothing(samples float64) {
+	f.cutoff.setTime(samples)
+	f.resonance.setTime(samples)
+}
+
+// IsBypassed returns true if the filter settings leave the sound unchanged,
+// which is the case for a low-pass filter fully open with no resonance
+func (f *ITFilter) IsBypassed() bool {
+	return f.Mode == ModeLowPass && f.Cutoff >= ITMaxCutoff && f.Resonance <= 0
+}
+
+func (f *ITFilter) updateCoefficients(cutoff float64, resonance float64) {
+	fc := ITCutoffFrequency(cutoff, f.ExtendedRange)
+	if limit := f.SampleRate * itMaxCutoffRatio; fc > limit {
+		fc = limit
+	}
+	dmpfac := math.Pow(10.0, -resonance*((24.0/128.0)/20.0))
+	r := f.SampleRate / (2.0 * math.Pi * fc)
+	d := dmpfac*r + dmpfac - 1.0
+	e := r * r
+
+	fg := 1.0 / (1.0 + d + e)
+	f.b0 = (d + e + e) / (1.0 + d + e)
+	f.b1 = -e / (1.0 + d + e)
+	switch f.Mode {
+	case ModeHighPass:
+		f.a0 = 1.0 - fg
+	default:
+		f.a0 = fg
+	}
+}
+
+// Filter processes a single multichannel sample
+func (f *ITFilter) Filter(dry volume.Matrix) volume.Matrix {
+	cutoff, cutoffChanged := f.cutoff.next(clampIT(f.Cutoff, ITMaxCutoff))
+	resonance, resonanceChanged := f.resonance.next(clampIT(f.Resonance, ITMaxResonance))
+	if cutoffChanged || resonanceChanged {
+		f.updateCoefficients(cutoff, resonance)
+	}
+
+	wet := dry
+	for c := 0; c < dry.Channels; c++ {
+		x := float64(dry.StaticMatrix[c])
+		y := f.a0*x + f.b0*clampState(f.y1[c]) + f.b1*clampState(f.y2[c])
+		f.y2[c] = f.y1[c]
+		if f.Mode == ModeHighPass {
+			f.y1[c] = y - x
+		} else {
+			f.y1[c] = y
+		}
+		wet.StaticMatrix[c] = volume.Volume(y)
+	}
+	return wet
+}
+
+// Reset clears the filter history and jumps the parameters to their current values
+func (f *ITFilter) Reset() {
+	for c := range f.y1 {
+		f.y1[c] = 0
+		f.y2[c] = 0
+	}
+	f.cutoff.reset()
+	f.resonance.reset()
+}
+
+func clampIT(v float64, limit float64) float64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > limit:
+		return limit
+	default:
+		return v
+	}
+}
+
+func clampState(v float64) float64 {
+	switch {
+	case v < -itFilterStateLimit:
+		return -itFilterStateLimit
+	case v > itFilterStateLimit:
+		return itFilterStateLimit
+	default:
+		return v
+	}
+}
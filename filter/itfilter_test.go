@@ -0,0 +1,199 @@
+package filter
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+func mono(v float64) volume.Matrix {
+	return volume.Matrix{
+		StaticMatrix: volume.StaticMatrix{volume.Volume(v)},
+		Channels:     1,
+	}
+}
+
+// The golden values below follow the filter setup of the Impulse Tracker mixer as documented
+// by OpenMPT (SetupChannelFilter), worked out independently in double precision.
+func TestITFilterGolden(t *testing.T) {
+	tests := []struct {
+		mode          Mode
+		sampleRate    float64
+		cutoff        float64
+		resonance     float64
+		extendedRange bool
+		a0, b0, b1    float64
+		impulse       []float64
+	}{
+		{ModeLowPass, 48000, 64, 0, false, 0.0105497092, 1.88187081, -0.892420518,
+			[]float64{0.0105497092, 0.0198531897, 0.0279463612, 0.0348740476, 0.040688546, 0.0454482713, 0.0492164818, 0.0520600906}},
+		{ModeLowPass, 44100, 100, 80, false, 0.103792311, 1.82260857, -0.926400883,
+			[]float64{0.103792311, 0.189172756, 0.248634598, 0.277913742, 0.276192656, 0.245931567, 0.192371862, 0.122787383}},
+		{ModeLowPass, 22050, 30, 127, false, 0.00781130907, 1.98600453, -0.993815842,
+			[]float64{0.00781130907, 0.0155132952, 0.0230464719, 0.0303530391, 0.0373773244, 0.0440662046, 0.0503695049, 0.0562403728}},
+		{ModeHighPass, 48000, 90, 40, false, 0.952590511, 1.84581923, -0.893228717,
+			[]float64{0.952590511, -0.0875093465, -0.119178917, -0.141816876, -0.155314285, -0.160007187, -0.156613164, -0.146156574}},
+		{ModeLowPass, 44100, 64, 64, true, 0.0279269991, 1.92410024, -0.952027236,
+			[]float64{0.0279269991, 0.0537343456, 0.0768030033, 0.0966201164, 0.112788238, 0.125030893, 0.133194496, 0.137246746}},
+	}
+
+	for _, tt := range tests {
+		f := NewITFilter(tt.mode, tt.sampleRate, tt.cutoff, tt.resonance, 0)
+		f.ExtendedRange = tt.extendedRange
+		for i, want := range tt.impulse {
+			x := 0.0
+			if i == 0 {
+				x = 1
+			}
+			got := float64(f.Filter(mono(x)).StaticMatrix[0])
+			if i == 0 {
+				for _, c := range []struct {
+					name      string
+					got, want float64
+				}{{"a0", f.a0, tt.a0}, {"b0", f.b0, tt.b0}, {"b1", f.b1, tt.b1}} {
+					if math.Abs(c.got-c.want) > 1e-8 {
+						t.Errorf("mode %v %vHz cutoff %v resonance %v: %s got %.9g, want %.9g",
+							tt.mode, tt.sampleRate, tt.cutoff, tt.resonance, c.name, c.got, c.want)
+					}
+				}
+			}
+			if math.Abs(got-want) > 1e-6 {
+				t.Errorf("mode %v %vHz cutoff %v resonance %v: impulse[%d] got %.9g, want %.9g",
+					tt.mode, tt.sampleRate, tt.cutoff, tt.resonance, i, got, want)
+			}
+		}
+	}
+}
+
+func TestITCutoffFrequency(t *testing.T) {
+	tests := []struct {
+		cutoff        float64
+		extendedRange bool
+		want          float64
+	}{
+		{0, false, 130.8127826502993},
+		{127, false, 5123.899203604501},
+		{24, false, 261.6255653005986},
+		{20, true, 261.6255653005986},
+	}
+	for _, tt := range tests {
+		if got := ITCutoffFrequency(tt.cutoff, tt.extendedRange); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("ITCutoffFrequency(%v, %v): got %v, want %v", tt.cutoff, tt.extendedRange, got, tt.want)
+		}
+	}
+}
+
+func TestITFilterUnityDCGain(t *testing.T) {
+	for _, cutoff := range []float64{0, 40, 80, 127} {
+		f := NewITFilter(ModeLowPass, 44100, cutoff, 0, 0)
+		var y float64
+		for i := 0; i < 200000; i++ {
+			y = float64(f.Filter(mono(0.5)).StaticMatrix[0])
+		}
+		if math.Abs(y-0.5) > 1e-4 {
+			t.Errorf("cutoff %v: DC output got %v, want 0.5", cutoff, y)
+		}
+	}
+}
+
+func TestITFilterStableAtExtremes(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, mode := range []Mode{ModeLowPass, ModeHighPass} {
+		for _, sampleRate := range []float64{8000, 44100, 192000} {
+			for _, cutoff := range []float64{0, 127} {
+				f := NewITFilter(mode, sampleRate, cutoff, ITMaxResonance, 0)
+				f.Filter(mono(0))
+				// with the history clamped, the output can't exceed this
+				bound := math.Abs(f.a0) + (math.Abs(f.b0)+math.Abs(f.b1))*itFilterStateLimit
+
+				for i := 0; i < 100000; i++ {
+					// full-scale square wave with noise, the hardest input for a resonant filter
+					x := 1.0
+					if (i/50)%2 == 0 {
+						x = -1
+					}
+					x = x*0.9 + (rng.Float64()*2-1)*0.1
+					y := float64(f.Filter(mono(x)).StaticMatrix[0])
+					if math.IsNaN(y) || math.IsInf(y, 0) || math.Abs(y) > bound+1e-6 {
+						t.Fatalf("mode %v %vHz cutoff %v: output %v out of bounds (%v) at sample %d", mode, sampleRate, cutoff, y, bound, i)
+					}
+				}
+
+				// once the input stops, the output must die away rather than self-oscillate
+				var y float64
+				for i := 0; i < 200000; i++ {
+					y = float64(f.Filter(mono(0)).StaticMatrix[0])
+				}
+				if math.Abs(y) > 1e-6 {
+					t.Errorf("mode %v %vHz cutoff %v: output %v did not decay", mode, sampleRate, cutoff, y)
+				}
+			}
+		}
+	}
+}
+
+// sineGain returns the steady-state gain of a filter for a sine at `freq` Hz
+func sineGain(filter func(volume.Matrix) volume.Matrix, sampleRate float64, freq float64, amplitude float64) float64 {
+	const settle = 4
+	period := sampleRate / freq
+	samples := int(period * 40)
+	var peak float64
+	for i := 0; i < samples*settle; i++ {
+		x := amplitude * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+		y := float64(filter(mono(x)).StaticMatrix[0])
+		if i >= samples*(settle-1) {
+			peak = math.Max(peak, math.Abs(y))
+		}
+	}
+	return peak / amplitude
+}
+
+// At cutoffs far below the sample rate the filter follows its analog prototype
+// H(s) = 1 / (1 + D*s/wc + (s/wc)^2), where D = 10^(-resonance*24/128/20) is the
+// damping factor. So the gain at the cutoff is 1/D (resonance*0.1875 dB), and with
+// no resonance the -3 dB point lies at sqrt((1+sqrt(5))/2) times the cutoff.
+func TestITFilterAnalogResponse(t *testing.T) {
+	const sampleRate = 192000
+	minus3dB := math.Sqrt((1 + math.Sqrt(5)) / 2)
+	tests := []struct {
+		cutoff    float64
+		resonance float64
+		freqRatio float64
+		wantDB    float64
+	}{
+		{0, 0, 1, 0},
+		{24, 0, 1, 0},
+		{0, 0, minus3dB, -10 * math.Log10(2)},
+		{24, 0, minus3dB, -10 * math.Log10(2)},
+		{0, 32, 1, 32 * 24.0 / 128.0},
+		{0, 64, 1, 64 * 24.0 / 128.0},
+		{0, 0, 4, -20 * math.Log10(math.Hypot(1-16, 4))},
+	}
+	for _, tt := range tests {
+		f := NewITFilter(ModeLowPass, sampleRate, tt.cutoff, tt.resonance, 0)
+		freq := ITCutoffFrequency(tt.cutoff, false) * tt.freqRatio
+		got := 20 * math.Log10(sineGain(f.Filter, sampleRate, freq, 0.1))
+		if math.Abs(got-tt.wantDB) > 0.1 {
+			t.Errorf("cutoff %v resonance %v at %.3f x fc: got %.3f dB, want %.3f dB", tt.cutoff, tt.resonance, tt.freqRatio, got, tt.wantDB)
+		}
+	}
+}
+
+func TestITFilterHighPassResponse(t *testing.T) {
+	f := NewITFilter(ModeHighPass, 44100, 40, 0, 0)
+	var y float64
+	for i := 0; i < 200000; i++ {
+		y = float64(f.Filter(mono(0.5)).StaticMatrix[0])
+	}
+	if math.Abs(y) > 1e-4 {
+		t.Errorf("high-pass DC output: got %v, want 0", y)
+	}
+
+	f.Reset()
+	fc := ITCutoffFrequency(40, false)
+	if got := sineGain(f.Filter, f.SampleRate, fc*16, 0.1); math.Abs(got-1) > 0.02 {
+		t.Errorf("high-pass gain well above the cutoff: got %v, want 1", got)
+	}
+}
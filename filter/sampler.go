@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Sampler applies a filter to the output of another sampler, so it can be
+// used as a per-voice stage anywhere a sampling.Sampler is accepted
+type Sampler struct {
+	sampling.Sampler
+	Filter Filter
+
+	wet   volume.Matrix
+	valid bool
+}
+
+// NewSampler returns a sampler that filters the output of `s`
+func NewSampler(s sampling.Sampler, f Filter) *Sampler {
+	return &Sampler{
+		Sampler: s,
+		Filter:  f,
+	}
+}
+
+// GetSample returns the filtered sample at the current position
+func (s *Sampler) GetSample() volume.Matrix {
+	if !s.valid {
+		s.wet = s.Sampler.GetSample()
+		if s.Filter != nil {
+			s.wet = s.Filter.Filter(s.wet)
+		}
+		s.valid = true
+	}
+	return s.wet
+}
+
+// Advance moves to the next sample, making sure the filter has seen the current one
+func (s *Sampler) Advance() {
+	if !s.valid {
+		s.GetSample()
+	}
+	s.Sampler.Advance()
+	s.valid = false
+}
+
+// IsEnded returns true if the underlying sampler has reached the end of its sample data
+func (s *Sampler) IsEnded() bool {
+	if e, ok := s.Sampler.(sampling.Ender); ok {
+		return e.IsEnded()
+	}
+	return false
+}
+
+// Release passes a key-off through to the underlying sampler
+func (s *Sampler) Release() {
+	if r, ok := s.Sampler.(sampling.Releaser); ok {
+		r.Release()
+	}
+}
@@ -0,0 +1,99 @@
+package fm
+
+import "errors"
+
+var (
+	// ErrInvalidOperator is returned when an algorithm refers to an operator that doesn't exist
+	ErrInvalidOperator = errors.New("invalid operator")
+	// ErrAlgorithmCycle is returned when an algorithm's connections form a loop
+	ErrAlgorithmCycle = errors.New("algorithm connections form a cycle")
+	// ErrNoCarriers is returned when an algorithm has no carriers
+	ErrNoCarriers = errors.New("algorithm has no carriers")
+)
+
+// Connection routes the output of one operator into the phase of another
+type Connection struct {
+	From int
+	To   int
+}
+
+// Algorithm describes how the operators of a patch connect. Modulators change the phase of the
+// operators they connect to, and the outputs of the carriers are summed into the voice's output.
+// Operators may only modulate themselves through their feedback.
+type Algorithm struct {
+	Connections []Connection
+	Carriers    []int
+}
+
+// OPNAlgorithms are the eight 4-operator algorithms of the YM2612 (OPN2) and related chips,
+// with operators numbered from 0. Operator 0 is the one with feedback on the original hardware.
+var OPNAlgorithms = [8]Algorithm{
+	// 0 -> 1 -> 2 -> 3
+	{Connections: []Connection{{0, 1}, {1, 2}, {2, 3}}, Carriers: []int{3}},
+	// (0 + 1) -> 2 -> 3
+	{Connections: []Connection{{0, 2}, {1, 2}, {2, 3}}, Carriers: []int{3}},
+	// (0 + (1 -> 2)) -> 3
+	{Connections: []Connection{{0, 3}, {1, 2}, {2, 3}}, Carriers: []int{3}},
+	// ((0 -> 1) + 2) -> 3
+	{Connections: []Connection{{0, 1}, {1, 3}, {2, 3}}, Carriers: []int{3}},
+	// (0 -> 1) + (2 -> 3)
+	{Connections: []Connection{{0, 1}, {2, 3}}, Carriers: []int{1, 3}},
+	// 0 -> (1 + 2 + 3)
+	{Connections: []Connection{{0, 1}, {0, 2}, {0, 3}}, Carriers: []int{1, 2, 3}},
+	// (0 -> 1) + 2 + 3
+	{Connections: []Connection{{0, 1}}, Carriers: []int{1, 2, 3}},
+	// 0 + 1 + 2 + 3
+	{Carriers: []int{0, 1, 2, 3}},
+}
+
+// OPLAlgorithms are the two 2-operator algorithms of the YM3812 (OPL2):
+// frequency modulation (0 -> 1) and additive synthesis (0 + 1)
+var OPLAlgorithms = [2]Algorithm{
+	{Connections: []Connection{{0, 1}}, Carriers: []int{1}},
+	{Carriers: []int{0, 1}},
+}
+
+// order returns the operators in an order where each comes after all of its modulators
+func (a *Algorithm) order(operators int) ([]int, error) {
+	if len(a.Carriers) == 0 {
+		return nil, ErrNoCarriers
+	}
+	for _, c := range a.Carriers {
+		if c < 0 || c >= operators {
+			return nil, ErrInvalidOperator
+		}
+	}
+
+	indegree := make([]int, operators)
+	for _, c := range a.Connections {
+		if c.From < 0 || c.From >= operators || c.To < 0 || c.To >= operators {
+			return nil, ErrInvalidOperator
+		}
+		if c.From == c.To {
+			return nil, ErrAlgorithmCycle
+		}
+		indegree[c.To]++
+	}
+
+	var order []int
+	for op, d := range indegree {
+		if d == 0 {
+			order = append(order, op)
+		}
+	}
+	for i := 0; i < len(order); i++ {
+		for _, c := range a.Connections {
+			if c.From != order[i] {
+				continue
+			}
+			indegree[c.To]--
+			if indegree[c.To] == 0 {
+				order = append(order, c.To)
+			}
+		}
+	}
+	if len(order) != operators {
+		return nil, ErrAlgorithmCycle
+	}
+	return order, nil
+}
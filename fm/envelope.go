@@ -0,0 +1,20 @@
+package fm
+
+import "github.com/gotracker/gomixing/envelope"
+
+// ADSR is an attack-decay-sustain-release envelope. Times are in output samples
+type ADSR struct {
+	// Attack is the time taken to rise from silence to full level
+	Attack float64
+	// Decay is the time taken to fall from full level to silence; the fall stops at the sustain level
+	Decay float64
+	// Sustain is the level (0-1) held while the key is down
+	Sustain float64
+	// Release is the time taken to fall from full level to silence after the key is released
+	Release float64
+}
+
+// generator returns a running envelope with these settings, starting from silence
+func (a ADSR) generator() envelope.ADSR {
+	return *envelope.NewADSR(a.Attack, a.Decay, a.Sustain, a.Release)
+}
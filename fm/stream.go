@@ -0,0 +1,112 @@
+package fm
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Stream plays a note of an FM patch as a sample stream, releasing the key at a fixed position.
+// Voices are generated in order, so reading a position before the last one read
+// regenerates the note from the start.
+type Stream struct {
+	// Frequency is the frequency of the note in cycles per output sample
+	Frequency float64
+	// Amplitude is the level applied to the sum of the carriers
+	Amplitude float64
+	// ReleaseAt is the position at which the key is released. Zero or less holds the key down
+	ReleaseAt int
+
+	patch Patch
+	voice *Voice
+	last  volume.Matrix
+
+	length    int
+	lengthFor int
+}
+
+// NewStream returns a stream playing a copy of the patch at `frequency` cycles per output sample,
+// or an error if the patch's algorithm is invalid
+func NewStream(patch *Patch, frequency float64) (*Stream, error) {
+	s := &Stream{
+		Frequency: frequency,
+		Amplitude: 1,
+		patch:     patch.clone(),
+	}
+	voice, err := NewVoice(&s.patch, frequency)
+	if err != nil {
+		return nil, err
+	}
+	s.voice = voice
+	return s, nil
+}
+
+// clone returns a copy of the patch that shares no memory with it
+func (p *Patch) clone() Patch {
+	return Patch{
+		Operators: append([]Operator(nil), p.Operators...),
+		Algorithm: Algorithm{
+			Connections: append([]Connection(nil), p.Algorithm.Connections...),
+			Carriers:    append([]int(nil), p.Algorithm.Carriers...),
+		},
+	}
+}
+
+// GetSample returns the sample at the position provided
+func (s *Stream) GetSample(pos sampling.Pos) volume.Matrix {
+	i := pos.Pos
+	if i < 0 {
+		return volume.Matrix{Channels: 1}
+	}
+	if i < s.voice.GetPosition().Pos-1 {
+		voice, err := NewVoice(&s.patch, s.Frequency)
+		if err != nil {
+			// unreachable, as the patch was validated by NewStream and can't be changed
+			return volume.Matrix{Channels: 1}
+		}
+		s.voice = voice
+		s.last = volume.Matrix{}
+	}
+	v := s.voice
+	v.Frequency = s.Frequency
+	v.Amplitude = s.Amplitude
+	for v.GetPosition().Pos <= i {
+		if s.ReleaseAt > 0 && v.GetPosition().Pos == s.ReleaseAt {
+			v.Release()
+		}
+		s.last = v.GetSample()
+		v.Advance()
+	}
+	return s.last
+}
+
+// Len returns the number of samples before all of the carriers have finished their release,
+// so that samplers playing the stream end with the note. It's math.MaxInt while the key is held down
+func (s *Stream) Len() int {
+	if s.ReleaseAt <= 0 {
+		return math.MaxInt
+	}
+	if s.lengthFor == s.ReleaseAt {
+		return s.length
+	}
+
+	length := 0
+	for _, c := range s.patch.Algorithm.Carriers {
+		env := s.patch.Operators[c].Envelope.generator()
+		n := 0
+		for !env.IsEnded() {
+			if n == s.ReleaseAt {
+				env.Release()
+			}
+			env.Advance()
+			n++
+		}
+		if n > length {
+			length = n
+		}
+	}
+	s.length = length
+	s.lengthFor = s.ReleaseAt
+	return length
+}
@@ -0,0 +1,117 @@
+package fm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/sampling"
+)
+
+func newTestStream(t *testing.T, tt voiceGolden) *Stream {
+	t.Helper()
+	s, err := NewStream(&tt.patch, tt.frequency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Amplitude = tt.amplitude
+	s.ReleaseAt = tt.releaseAt
+	return s
+}
+
+func TestStreamMatchesVoice(t *testing.T) {
+	for _, tt := range voiceGoldens {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStream(t, tt)
+			v, err := NewVoice(&tt.patch, tt.frequency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v.Amplitude = tt.amplitude
+			for i := 0; i < 100; i++ {
+				if i == tt.releaseAt {
+					v.Release()
+				}
+				want := v.GetSample()
+				if got := s.GetSample(sampling.Pos{Pos: i}); got != want {
+					t.Fatalf("sample %d: got %v, want %v", i, got, want)
+				}
+				v.Advance()
+			}
+		})
+	}
+}
+
+func TestStreamLen(t *testing.T) {
+	for _, tt := range voiceGoldens {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestStream(t, tt)
+			if got := s.Len(); got != tt.endAt {
+				t.Errorf("Len: got %d, want %d", got, tt.endAt)
+			}
+			for i := s.Len(); i < s.Len()+20; i++ {
+				if got := s.GetSample(sampling.Pos{Pos: i}).StaticMatrix[0]; got != 0 {
+					t.Errorf("sample %d after the end: got %v, want 0", i, got)
+				}
+			}
+
+			// a sampler playing the stream ends with the note, so voice pools can reclaim it
+			smp := sampling.NewSampler(s, sampling.Pos{}, 1)
+			ender := smp.(sampling.Ender)
+			for i := 0; i < tt.endAt; i++ {
+				if ender.IsEnded() {
+					t.Fatalf("sampler ended at %d, want %d", i, tt.endAt)
+				}
+				smp.Advance()
+			}
+			if !ender.IsEnded() {
+				t.Errorf("sampler has not ended at %d", tt.endAt)
+			}
+
+			s.ReleaseAt = 0
+			if got := s.Len(); got != math.MaxInt {
+				t.Errorf("Len with the key held: got %d, want %d", got, math.MaxInt)
+			}
+		})
+	}
+}
+
+func TestStreamRewind(t *testing.T) {
+	tt := voiceGoldens[0]
+	s := newTestStream(t, tt)
+	first := make([]float64, 100)
+	for i := range first {
+		first[i] = float64(s.GetSample(sampling.Pos{Pos: i}).StaticMatrix[0])
+	}
+	for _, i := range []int{10, 10, 9, 50, 0, 99, 48} {
+		if got := float64(s.GetSample(sampling.Pos{Pos: i}).StaticMatrix[0]); got != first[i] {
+			t.Errorf("sample %d read again: got %v, want %v", i, got, first[i])
+		}
+	}
+	if got := s.GetSample(sampling.Pos{Pos: -1}); got.Channels != 1 || got.StaticMatrix[0] != 0 {
+		t.Errorf("negative position: got %v, want silence", got)
+	}
+}
+
+func TestStreamKeepsCopyOfPatch(t *testing.T) {
+	tt := voiceGoldens[0]
+	patch := tt.patch.clone()
+	s, err := NewStream(&patch, tt.frequency)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Amplitude = tt.amplitude
+	s.ReleaseAt = tt.releaseAt
+	want := float64(s.GetSample(sampling.Pos{Pos: 20}).StaticMatrix[0])
+
+	// breaking the caller's patch must not affect the stream, even when it regenerates the note
+	patch.Algorithm.Carriers = nil
+	patch.Algorithm.Connections[0] = Connection{From: 3, To: 0}
+	patch.Operators[3].Level = 0
+	if got := float64(s.GetSample(sampling.Pos{Pos: 20}).StaticMatrix[0]); got != want {
+		t.Errorf("after changing the caller's patch: got %v, want %v", got, want)
+	}
+
+	if _, err := NewStream(&patch, tt.frequency); err == nil {
+		t.Errorf("NewStream with an invalid patch: got no error")
+	}
+}
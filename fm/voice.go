@@ -0,0 +1,157 @@
+package fm
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/envelope"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Operator is a sine oscillator with its own envelope, the building block of an FM voice
+type Operator struct {
+	// Ratio is the operator's frequency as a multiple of the voice frequency
+	Ratio float64
+	// Detune offsets the operator's frequency, in cents
+	Detune float64
+	// Level is the peak output level. For a modulator, a level of 1 sweeps the phase of the
+	// operators it modulates by up to one cycle either way
+	Level float64
+	// Feedback is the amount of the operator's own output fed back into its phase
+	Feedback float64
+	// Envelope shapes the operator's level over time
+	Envelope ADSR
+}
+
+// Patch is a set of operators and the algorithm connecting them
+type Patch struct {
+	Operators []Operator
+	Algorithm Algorithm
+}
+
+// Voice plays a note of an FM patch. It implements sampling.Sampler, and the optional
+// sampling.Releaser and sampling.Ender interfaces for key-off and voice reclaiming.
+// The output is the sum of the carriers, so it is a pure function of the patch,
+// the frequency and when the voice is released.
+type Voice struct {
+	// Frequency is the frequency of the note in cycles per output sample
+	Frequency float64
+	// Amplitude is the level applied to the sum of the carriers
+	Amplitude float64
+
+	patch    *Patch
+	order    []int
+	mods     [][]int
+	ops      []operatorState
+	pos      sampling.Pos
+	computed bool
+	output   float64
+}
+
+type operatorState struct {
+	phase   float64
+	env     envelope.ADSR
+	out     float64
+	history [2]float64
+	carrier bool
+}
+
+// NewVoice returns a voice playing the patch at `frequency` cycles per output sample,
+// or an error if the patch's algorithm is invalid
+func NewVoice(patch *Patch, frequency float64) (*Voice, error) {
+	order, err := patch.Algorithm.order(len(patch.Operators))
+	if err != nil {
+		return nil, err
+	}
+	v := &Voice{
+		Frequency: frequency,
+		Amplitude: 1,
+		patch:     patch,
+		order:     order,
+		mods:      make([][]int, len(patch.Operators)),
+		ops:       make([]operatorState, len(patch.Operators)),
+	}
+	for _, c := range patch.Algorithm.Connections {
+		v.mods[c.To] = append(v.mods[c.To], c.From)
+	}
+	for _, c := range patch.Algorithm.Carriers {
+		v.ops[c].carrier = true
+	}
+	for i := range v.ops {
+		v.ops[i].env = patch.Operators[i].Envelope.generator()
+	}
+	return v, nil
+}
+
+// GetPosition returns the number of samples played
+func (v *Voice) GetPosition() sampling.Pos {
+	return v.pos
+}
+
+// GetSample returns the current sample
+func (v *Voice) GetSample() volume.Matrix {
+	v.compute()
+	return volume.Matrix{
+		StaticMatrix: volume.StaticMatrix{volume.Volume(v.output)},
+		Channels:     1,
+	}
+}
+
+// Advance moves the voice on by one sample
+func (v *Voice) Advance() {
+	v.compute()
+	for i := range v.ops {
+		op := &v.patch.Operators[i]
+		st := &v.ops[i]
+		st.history[1] = st.history[0]
+		st.history[0] = st.out
+
+		freq := v.Frequency * op.Ratio * math.Pow(2, op.Detune/1200)
+		st.phase += freq
+		st.phase -= math.Floor(st.phase)
+		st.env.Advance()
+	}
+	v.pos.Add(1)
+	v.computed = false
+}
+
+// compute works out the operator outputs for the current sample
+func (v *Voice) compute() {
+	if v.computed {
+		return
+	}
+	var sum float64
+	for _, i := range v.order {
+		op := &v.patch.Operators[i]
+		st := &v.ops[i]
+		var mod float64
+		for _, m := range v.mods[i] {
+			mod += v.ops[m].out
+		}
+		// feedback uses the average of the last two outputs, as the OPL and OPN chips do
+		mod += op.Feedback * (st.history[0] + st.history[1]) / 2
+		st.out = op.Level * st.env.Value() * math.Sin(2*math.Pi*(st.phase+mod))
+		if st.carrier {
+			sum += st.out
+		}
+	}
+	v.output = sum * v.Amplitude
+	v.computed = true
+}
+
+// Release releases the key, moving the operator envelopes into their release stage
+func (v *Voice) Release() {
+	for i := range v.ops {
+		v.ops[i].env.Release()
+	}
+}
+
+// IsEnded returns true once all of the carriers have finished their release
+func (v *Voice) IsEnded() bool {
+	for _, st := range v.ops {
+		if st.carrier && !st.env.IsEnded() {
+			return false
+		}
+	}
+	return true
+}
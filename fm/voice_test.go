@@ -0,0 +1,210 @@
+package fm
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/envelope"
+)
+
+// goldenIndices are the sample positions checked by the golden-output tests
+var goldenIndices = []int{0, 1, 2, 3, 5, 8, 13, 21, 34, 47, 48, 50, 55, 64, 77, 78, 90}
+
+type voiceGolden struct {
+	name      string
+	patch     Patch
+	frequency float64
+	amplitude float64
+	releaseAt int
+	// want are the samples at goldenIndices, and endAt is the first sample at which the voice reports it has ended
+	want  []float64
+	endAt int
+}
+
+// The golden values come from a separate double-precision model of the operators, envelopes and feedback
+var voiceGoldens = []voiceGolden{
+	{
+		name: "opn algorithm 0",
+		patch: Patch{
+			Algorithm: OPNAlgorithms[0],
+			Operators: []Operator{
+				{Ratio: 1, Level: 0.5, Feedback: 0.3, Envelope: ADSR{Attack: 2, Decay: 30, Sustain: 0.5, Release: 12}},
+				{Ratio: 2, Detune: 7, Level: 0.4, Envelope: ADSR{Attack: 3, Decay: 25, Sustain: 0.7, Release: 15}},
+				{Ratio: 1, Detune: -5, Level: 0.3, Envelope: ADSR{Attack: 1, Decay: 40, Sustain: 0.8, Release: 10}},
+				{Ratio: 1, Level: 1, Envelope: ADSR{Attack: 4, Decay: 20, Sustain: 0.6, Release: 30}},
+			},
+		},
+		frequency: 440.0 / 8000,
+		amplitude: 0.8,
+		releaseAt: 48,
+		want: []float64{
+			0, 0.17721453829935913, 0.2448618325726741, -0.3809087420365518, 0.6340557037256696,
+			-0.5864878575964709, -0.40037153472070114, 0.47539767391338983, -0.4128938549354899,
+			0.3814621951476924, 0.17190401530741856, -0.355278707840143, 0.06535778505240497,
+			-0.0066844391234299715, 0, 0, 0,
+		},
+		endAt: 67,
+	},
+	{
+		name: "opl algorithm 0",
+		patch: Patch{
+			Algorithm: OPLAlgorithms[0],
+			Operators: []Operator{
+				{Ratio: 1, Level: 0.7, Feedback: 0.5, Envelope: ADSR{Attack: 0, Decay: 10, Sustain: 0.4, Release: 20}},
+				{Ratio: 3, Level: 1, Envelope: ADSR{Attack: 5, Decay: 15, Sustain: 0.5, Release: 25}},
+			},
+		},
+		frequency: 1000.0 / 22050,
+		amplitude: 1,
+		releaseAt: 40,
+		want: []float64{
+			0, 0.17351909229708531, -0.39958239677810514, -0.24451340575609246, -0.5301108915708135,
+			0.7101059756658468, -0.4206254279957701, -0.2914205190073815, -0.17708035953685672,
+			0.10486615833507644, -0.034408553166244915, -0.09456344796221916, 0, 0, 0, 0, 0,
+		},
+		endAt: 53,
+	},
+}
+
+func TestVoiceGolden(t *testing.T) {
+	for _, tt := range voiceGoldens {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := NewVoice(&tt.patch, tt.frequency)
+			if err != nil {
+				t.Fatal(err)
+			}
+			v.Amplitude = tt.amplitude
+
+			samples := make([]float64, 100)
+			endAt := -1
+			for i := range samples {
+				if i == tt.releaseAt {
+					v.Release()
+				}
+				if pos := v.GetPosition(); pos.Pos != i {
+					t.Fatalf("sample %d: position %d", i, pos.Pos)
+				}
+				samp := v.GetSample()
+				if samp.Channels != 1 {
+					t.Fatalf("sample %d: %d channels, want 1", i, samp.Channels)
+				}
+				samples[i] = float64(samp.StaticMatrix[0])
+				if endAt < 0 && v.IsEnded() {
+					endAt = i
+				}
+				v.Advance()
+			}
+
+			for k, i := range goldenIndices {
+				if got, want := samples[i], tt.want[k]; math.Abs(got-want) > 1e-6 {
+					t.Errorf("sample %d: got %v, want %v", i, got, want)
+				}
+			}
+			if endAt != tt.endAt {
+				t.Errorf("voice ended at sample %d, want %d", endAt, tt.endAt)
+			}
+		})
+	}
+}
+
+// render returns the first `n` samples of a voice
+func render(v *Voice, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = float64(v.GetSample().StaticMatrix[0])
+		v.Advance()
+	}
+	return out
+}
+
+func TestVoiceSingleOperator(t *testing.T) {
+	env := ADSR{Attack: 8, Decay: 16, Sustain: 0.5, Release: 10}
+	tests := []struct {
+		name  string
+		op    Operator
+		ratio float64
+	}{
+		{"unison", Operator{Ratio: 1, Level: 0.8, Envelope: env}, 1},
+		{"ratio", Operator{Ratio: 3, Level: 0.5, Envelope: env}, 3},
+		{"detuned an octave", Operator{Ratio: 1, Detune: 1200, Level: 1, Envelope: env}, 2},
+	}
+	const freq = 0.013
+	for _, tt := range tests {
+		v, err := NewVoice(&Patch{Operators: []Operator{tt.op}, Algorithm: Algorithm{Carriers: []int{0}}}, freq)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v.Amplitude = 0.5
+
+		// an unmodulated carrier is Level * env * sin(2*pi*f*t), with the envelope stepped once per sample
+		e := envelope.NewADSR(env.Attack, env.Decay, env.Sustain, env.Release)
+		for i, got := range render(v, 200) {
+			want := 0.5 * tt.op.Level * e.Value() * math.Sin(2*math.Pi*freq*tt.ratio*float64(i))
+			if math.Abs(got-want) > 1e-6 {
+				t.Errorf("%s sample %d: got %v, want %v", tt.name, i, got, want)
+			}
+			e.Advance()
+		}
+	}
+}
+
+func TestVoiceFeedback(t *testing.T) {
+	const (
+		freq     = 0.02
+		level    = 0.9
+		feedback = 0.7
+	)
+	op := Operator{Ratio: 1, Level: level, Feedback: feedback, Envelope: ADSR{Sustain: 1}}
+	v, err := NewVoice(&Patch{Operators: []Operator{op}, Algorithm: Algorithm{Carriers: []int{0}}}, freq)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the phase is offset by the feedback times the average of the previous two outputs
+	e := envelope.NewADSR(0, 0, 1, 0)
+	var prev [2]float64
+	for i, got := range render(v, 200) {
+		want := level * e.Value() * math.Sin(2*math.Pi*(freq*float64(i)+feedback*(prev[0]+prev[1])/2))
+		if math.Abs(got-want) > 1e-6 {
+			t.Fatalf("sample %d: got %v, want %v", i, got, want)
+		}
+		prev[1], prev[0] = prev[0], want
+		e.Advance()
+	}
+}
+
+func TestVoiceInvalidAlgorithm(t *testing.T) {
+	ops := make([]Operator, 2)
+	tests := []struct {
+		name string
+		alg  Algorithm
+		want error
+	}{
+		{name: "no carriers", alg: Algorithm{Connections: []Connection{{0, 1}}}, want: ErrNoCarriers},
+		{name: "carrier out of range", alg: Algorithm{Carriers: []int{2}}, want: ErrInvalidOperator},
+		{name: "connection out of range", alg: Algorithm{Connections: []Connection{{0, 5}}, Carriers: []int{1}}, want: ErrInvalidOperator},
+		{name: "self connection", alg: Algorithm{Connections: []Connection{{1, 1}}, Carriers: []int{1}}, want: ErrAlgorithmCycle},
+		{name: "cycle", alg: Algorithm{Connections: []Connection{{0, 1}, {1, 0}}, Carriers: []int{1}}, want: ErrAlgorithmCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewVoice(&Patch{Operators: ops, Algorithm: tt.alg}, 0.01); !errors.Is(err, tt.want) {
+				t.Errorf("got error %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlgorithmsAreValid(t *testing.T) {
+	for i, alg := range OPNAlgorithms {
+		if _, err := alg.order(4); err != nil {
+			t.Errorf("OPN algorithm %d: %v", i, err)
+		}
+	}
+	for i, alg := range OPLAlgorithms {
+		if _, err := alg.order(2); err != nil {
+			t.Errorf("OPL algorithm %d: %v", i, err)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package lfo
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gotracker/gomixing/envelope"
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Waveform is the shape of an LFO's cycle
+type Waveform int
+
+const (
+	// WaveformSine is a sine wave
+	WaveformSine = Waveform(iota)
+	// WaveformRampUp rises from -1 to 1 over each cycle
+	WaveformRampUp
+	// WaveformRampDown falls from 1 to -1 over each cycle
+	WaveformRampDown
+	// WaveformSquare is 1 for the first half of each cycle and -1 for the second
+	WaveformSquare
+	// WaveformRandom takes a new random value every step
+	WaveformRandom
+	// WaveformSampleAndHold takes a new random value at the start of every cycle
+	WaveformSampleAndHold
+)
+
+// LFO is a low-frequency oscillator for periodic modulation such as vibrato, tremolo and panbrello.
+// Each call to Advance moves it on by one step, which may be an output sample or a tracker tick.
+// It implements envelope.Generator, so it can drive the envelopes of an envelope.Sampler directly.
+type LFO struct {
+	Waveform Waveform
+	// Rate is the speed of the LFO in cycles per step
+	Rate float64
+	// Depth is the peak value of the LFO's output
+	Depth float64
+	// Phase is the phase (0-1) the LFO starts at.
+	// It takes effect when the LFO is first used after New, Reset or a retrigger
+	Phase float64
+	// Retrigger restarts the LFO at its starting phase whenever it is triggered
+	Retrigger bool
+	// Seed seeds the random waveforms, so they repeat for the same seed.
+	// It takes effect when the random values are first used after New or Reset
+	Seed int64
+
+	phase   float64
+	started bool
+	held    float64
+	rng     *rand.Rand
+}
+
+// New returns an LFO with the waveform, rate (in cycles per step) and depth provided
+func New(waveform Waveform, rate float64, depth float64) *LFO {
+	l := &LFO{
+		Waveform:  waveform,
+		Rate:      rate,
+		Depth:     depth,
+		Retrigger: true,
+	}
+	l.Reset()
+	return l
+}
+
+// Value returns the output at the current step, between -Depth and Depth
+func (l *LFO) Value() float64 {
+	l.start()
+	var v float64
+	switch l.Waveform {
+	case WaveformSine:
+		v = math.Sin(2 * math.Pi * l.phase)
+	case WaveformRampUp:
+		v = 2*l.phase - 1
+	case WaveformRampDown:
+		v = 1 - 2*l.phase
+	case WaveformSquare:
+		v = 1
+		if l.phase >= 0.5 {
+			v = -1
+		}
+	case WaveformRandom, WaveformSampleAndHold:
+		l.seed()
+		v = l.held
+	}
+	return v * l.Depth
+}
+
+// Advance moves on by one step
+func (l *LFO) Advance() {
+	l.start()
+	l.phase += l.Rate
+	wrapped := l.phase >= 1 || l.phase < 0
+	l.phase -= math.Floor(l.phase)
+	if l.Waveform == WaveformRandom || (l.Waveform == WaveformSampleAndHold && wrapped) {
+		l.seed()
+		l.held = l.random()
+	}
+}
+
+// Trigger is called when a new note starts, restarting the LFO if Retrigger is set
+func (l *LFO) Trigger() {
+	if l.Retrigger {
+		l.started = false
+	}
+}
+
+// Release does nothing; an LFO runs for as long as it is advanced
+func (l *LFO) Release() {
+}
+
+// IsEnded always returns false; an LFO runs for as long as it is advanced
+func (l *LFO) IsEnded() bool {
+	return false
+}
+
+// Reset restarts the LFO at its starting phase and restarts its random waveforms from Seed
+func (l *LFO) Reset() {
+	l.started = false
+	l.rng = nil
+}
+
+// start moves to Phase, if the LFO hasn't been started since the last reset or retrigger
+func (l *LFO) start() {
+	if !l.started {
+		l.phase = l.Phase - math.Floor(l.Phase)
+		l.started = true
+	}
+}
+
+// seed starts the random values from Seed, if they haven't been started since the last reset
+func (l *LFO) seed() {
+	if l.rng == nil {
+		l.rng = rand.New(rand.NewSource(l.Seed))
+		l.held = l.random()
+	}
+}
+
+func (l *LFO) random() float64 {
+	return l.rng.Float64()*2 - 1
+}
+
+// Period applies vibrato to a sampler period (the amount the sample position moves per output sample),
+// treating the LFO's output as semitones
+func (l *LFO) Period(period float32) float32 {
+	return period * float32(envelope.PitchRatio(l))
+}
+
+// Volume applies tremolo to a volume, swinging it between full volume at the LFO's peak
+// and 1-Depth of it at the LFO's trough, so tremolo never makes a sound louder
+func (l *LFO) Volume(vol volume.Volume) volume.Volume {
+	return vol * volume.Volume(l.tremolo())
+}
+
+// Pan applies panbrello to a panning position, treating the LFO's output as a
+// panning envelope value (-1 for full left to 1 for full right)
+func (l *LFO) Pan(pos panning.Position) panning.Position {
+	return envelope.Pan(pos, l)
+}
+
+// Tremolo returns a generator for the volume envelope of an envelope.Sampler that
+// applies this LFO as tremolo, swinging between full volume and 1-Depth, the same as Volume
+func (l *LFO) Tremolo() envelope.Generator {
+	return tremolo{l}
+}
+
+type tremolo struct {
+	*LFO
+}
+
+func (t tremolo) Value() float64 {
+	return t.tremolo()
+}
+
+// tremolo returns the fraction of the volume to play at the current step
+func (l *LFO) tremolo() float64 {
+	return math.Max(0, math.Min(1-(l.Depth-l.Value())/2, 1))
+}
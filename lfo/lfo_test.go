@@ -0,0 +1,147 @@
+package lfo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+func values(l *LFO, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = l.Value()
+		l.Advance()
+	}
+	return out
+}
+
+func equalValues(a []float64, b []float64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return len(a) == len(b)
+}
+
+func TestLFOWaveforms(t *testing.T) {
+	tests := []struct {
+		waveform Waveform
+		want     []float64
+	}{
+		{waveform: WaveformSine, want: []float64{0, 2, 0, -2, 0}},
+		{waveform: WaveformRampUp, want: []float64{-2, -1, 0, 1, -2}},
+		{waveform: WaveformRampDown, want: []float64{2, 1, 0, -1, 2}},
+		{waveform: WaveformSquare, want: []float64{2, 2, -2, -2, 2}},
+	}
+	for _, tt := range tests {
+		got := values(New(tt.waveform, 0.25, 2), len(tt.want))
+		for i := range got {
+			if math.Abs(got[i]-tt.want[i]) > 1e-12 {
+				t.Errorf("waveform %d: got %v, want %v", tt.waveform, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestLFOSeedAfterNew(t *testing.T) {
+	for _, waveform := range []Waveform{WaveformRandom, WaveformSampleAndHold} {
+		a := New(waveform, 0.3, 1)
+		a.Seed = 42
+		b := New(waveform, 0.3, 1)
+		b.Seed = 42
+		c := New(waveform, 0.3, 1)
+		c.Seed = 7
+
+		va, vb, vc := values(a, 32), values(b, 32), values(c, 32)
+		if !equalValues(va, vb) {
+			t.Errorf("waveform %d: the same seed gave different values:\n%v\n%v", waveform, va, vb)
+		}
+		if equalValues(va, vc) {
+			t.Errorf("waveform %d: different seeds gave the same values", waveform)
+		}
+
+		a.Reset()
+		if again := values(a, 32); !equalValues(va, again) {
+			t.Errorf("waveform %d: reset didn't repeat the values:\n%v\n%v", waveform, va, again)
+		}
+		for _, v := range va {
+			if v < -1 || v > 1 {
+				t.Fatalf("waveform %d: value %v out of range", waveform, v)
+			}
+		}
+	}
+}
+
+func TestLFOSampleAndHold(t *testing.T) {
+	l := New(WaveformSampleAndHold, 0.25, 1)
+	l.Seed = 3
+	v := values(l, 12)
+	for i := range v {
+		if i%4 != 0 && v[i] != v[i-1] {
+			t.Errorf("step %d: value changed within a cycle: %v", i, v)
+		}
+	}
+	if v[0] == v[4] && v[4] == v[8] {
+		t.Errorf("value didn't change between cycles: %v", v)
+	}
+}
+
+func TestLFOTrigger(t *testing.T) {
+	l := New(WaveformRampUp, 0.1, 1)
+	l.Phase = 0.5
+	l.Advance()
+	l.Trigger()
+	if got := l.Value(); got != 0 {
+		t.Errorf("after retrigger: got %v, want 0", got)
+	}
+	l.Retrigger = false
+	l.Advance()
+	l.Trigger()
+	if got := l.Value(); math.Abs(got-0.2) > 1e-12 {
+		t.Errorf("trigger without retrigger: got %v, want 0.2", got)
+	}
+}
+
+func TestLFOPhaseAfterNew(t *testing.T) {
+	tests := []struct {
+		phase float64
+		want  []float64
+	}{
+		{0, []float64{-1, -0.5, 0, 0.5, -1}},
+		{0.5, []float64{0, 0.5, -1, -0.5, 0}},
+		{1.25, []float64{-0.5, 0, 0.5, -1, -0.5}},
+		{-0.25, []float64{0.5, -1, -0.5, 0, 0.5}},
+	}
+	for _, tt := range tests {
+		l := New(WaveformRampUp, 0.25, 1)
+		l.Phase = tt.phase
+		if got := values(l, len(tt.want)); !equalValues(got, tt.want) {
+			t.Errorf("phase %v: got %v, want %v", tt.phase, got, tt.want)
+		}
+		l.Reset()
+		if got := values(l, len(tt.want)); !equalValues(got, tt.want) {
+			t.Errorf("phase %v after reset: got %v, want %v", tt.phase, got, tt.want)
+		}
+	}
+}
+
+func TestLFOTremolo(t *testing.T) {
+	for _, depth := range []float64{0.25, 0.5, 1, 1.5} {
+		l := New(WaveformSquare, 0.5, depth)
+		g := l.Tremolo()
+		// the square wave's peak plays at full volume and its trough at 1-Depth
+		want := []float64{1, math.Max(0, 1-depth), 1, math.Max(0, 1-depth)}
+		for i, w := range want {
+			if got := g.Value(); math.Abs(got-w) > 1e-12 {
+				t.Errorf("depth %v step %d generator: got %v, want %v", depth, i, got, w)
+			}
+			if got := l.Volume(0.5); math.Abs(float64(got)-0.5*w) > 1e-6 {
+				t.Errorf("depth %v step %d volume: got %v, want %v", depth, i, got, volume.Volume(0.5*w))
+			}
+			l.Advance()
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package metering
+
+import "math"
+
+// kWeighting is the two-stage K-weighting pre-filter of ITU-R BS.1770: a high shelf modelling
+// the acoustic effect of the head followed by a high-pass (the RLB weighting curve)
+type kWeighting struct {
+	shelf, highPass biquad64
+}
+
+func newKWeighting(sampleRate float64) kWeighting {
+	var k kWeighting
+
+	// stage 1: high shelf, +4 dB above ~1.5 kHz
+	f0 := 1681.974450955533
+	g := 3.999843853973347
+	q := 0.7071752369554196
+	t := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + t/q + t*t
+	k.shelf = biquad64{
+		b0: (vh + vb*t/q + t*t) / a0,
+		b1: 2 * (t*t - vh) / a0,
+		b2: (vh - vb*t/q + t*t) / a0,
+		a1: 2 * (t*t - 1) / a0,
+		a2: (1 - t/q + t*t) / a0,
+	}
+
+	// stage 2: high-pass at ~38 Hz
+	f0 = 38.13547087602444
+	q = 0.5003270373238773
+	t = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + t/q + t*t
+	k.highPass = biquad64{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (t*t - 1) / a0,
+		a2: (1 - t/q + t*t) / a0,
+	}
+	return k
+}
+
+func (k *kWeighting) process(x float64) float64 {
+	return k.highPass.process(k.shelf.process(x))
+}
+
+func (k *kWeighting) reset() {
+	k.shelf.reset()
+	k.highPass.reset()
+}
+
+// biquad64 is a double-precision transposed direct form II biquad
+type biquad64 struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+func (f *biquad64) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+func (f *biquad64) reset() {
+	f.z1 = 0
+	f.z2 = 0
+}
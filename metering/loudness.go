@@ -0,0 +1,340 @@
+package metering
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+const (
+	// loudnessStepsPerSecond is the rate at which gating blocks are started (75% overlap of 400ms blocks)
+	loudnessStepsPerSecond = 10
+	// momentarySteps is the number of steps in the 400ms momentary window
+	momentarySteps = 4
+	// shortTermSteps is the number of steps in the 3s short-term window
+	shortTermSteps = 30
+
+	// AbsoluteGate is the absolute gating threshold in LUFS
+	AbsoluteGate = -70.0
+	// integratedRelativeGate is the relative gating threshold in LU for integrated loudness
+	integratedRelativeGate = -10.0
+	// rangeRelativeGate is the relative gating threshold in LU for loudness range
+	rangeRelativeGate = -20.0
+	// rangeLowPercentile and rangeHighPercentile bound the loudness range distribution
+	rangeLowPercentile  = 0.10
+	rangeHighPercentile = 0.95
+
+	// loudnessBinsPerLU is the resolution of the loudness histograms
+	loudnessBinsPerLU = 10
+	// loudnessHistogramMax is the loudness in LUFS of the top of the histograms; louder blocks go in the top bin
+	loudnessHistogramMax = 10.0
+	loudnessBins         = int(loudnessHistogramMax-AbsoluteGate) * loudnessBinsPerLU
+)
+
+// ChannelWeights returns the BS.1770 channel weights for a layout with the number of channels provided.
+// Mono, stereo and 3-channel layouts weight every channel equally; a quad layout is treated as
+// front left, front right, surround left and surround right, with the surrounds weighted by 1.41 (+1.5 dB)
+func ChannelWeights(channels int) [volume.MaxChannels]float64 {
+	w := [volume.MaxChannels]float64{1, 1, 1, 1}
+	if channels == 4 {
+		w[2] = 1.41
+		w[3] = 1.41
+	}
+	return w
+}
+
+// LoudnessLevels is a snapshot of a loudness meter's readings.
+// Loudness values are in LUFS and are negative infinity until enough signal has been measured
+type LoudnessLevels struct {
+	// Momentary is the loudness of the last 400ms
+	Momentary float64
+	// ShortTerm is the loudness of the last 3s
+	ShortTerm float64
+	// Integrated is the gated loudness of everything measured since the last reset
+	Integrated float64
+	// Range is the loudness range (LRA) in LU of everything measured since the last reset
+	Range float64
+}
+
+// LoudnessMeter measures loudness according to ITU-R BS.1770 and EBU R128.
+// Measuring is done on the mixing goroutine, while the readings may be taken from any goroutine.
+// A LoudnessMeter is also a mixing.Effect that leaves the audio untouched.
+type LoudnessMeter struct {
+	// Weights are the per-channel weights applied when summing channel power
+	Weights [volume.MaxChannels]float64
+
+	filters   [volume.MaxChannels]kWeighting
+	stepLen   int
+	stepPos   int
+	stepPower float64
+	steps     [shortTermSteps]float64
+	stepHead  int
+	numSteps  int
+
+	mu         sync.Mutex
+	blocks     loudnessHistogram
+	shortTerms loudnessHistogram
+	momentary  float64
+	shortTerm  float64
+}
+
+// loudnessHistogram collects block powers above the absolute gate in 0.1 LU bins, so that
+// gated measurements over any length of audio take a fixed amount of memory
+type loudnessHistogram struct {
+	counts [loudnessBins]int
+	power  [loudnessBins]float64
+}
+
+// add adds the mean power of a block to the histogram. Blocks at or below the absolute gate are dropped
+func (h *loudnessHistogram) add(p float64) {
+	lufs := powerToLUFS(p)
+	if !(lufs > AbsoluteGate) {
+		return
+	}
+	bin := int((lufs - AbsoluteGate) * loudnessBinsPerLU)
+	if bin >= loudnessBins {
+		bin = loudnessBins - 1
+	}
+	h.counts[bin]++
+	h.power[bin] += p
+}
+
+// binPower returns the mean power of the blocks in a bin
+func (h *loudnessHistogram) binPower(bin int) float64 {
+	return h.power[bin] / float64(h.counts[bin])
+}
+
+// gatedMean returns the mean power of the blocks above the threshold (in LUFS) and the number of blocks used.
+// A bin is counted as a whole, depending on whether the mean of its blocks is above the threshold
+func (h *loudnessHistogram) gatedMean(threshold float64) (float64, int) {
+	gate := lufsToPower(threshold)
+	var sum float64
+	n := 0
+	for bin, count := range h.counts {
+		if count != 0 && h.binPower(bin) > gate {
+			sum += h.power[bin]
+			n += count
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return sum / float64(n), n
+}
+
+// percentile returns the loudness in LUFS of the block at a fraction of the way through
+// the `n` blocks above the threshold bin, in order of loudness
+func (h *loudnessHistogram) percentile(firstBin int, n int, fraction float64) float64 {
+	idx := int(math.Round(float64(n-1) * fraction))
+	for bin := firstBin; bin < loudnessBins; bin++ {
+		if idx < h.counts[bin] {
+			return powerToLUFS(h.binPower(bin))
+		}
+		idx -= h.counts[bin]
+	}
+	return math.Inf(-1)
+}
+
+func (h *loudnessHistogram) reset() {
+	*h = loudnessHistogram{}
+}
+
+// NewLoudnessMeter returns a loudness meter for the sample rate and channel layout provided
+func NewLoudnessMeter(sampleRate float64, channels int) *LoudnessMeter {
+	l := &LoudnessMeter{
+		Weights:   ChannelWeights(channels),
+		stepLen:   int(math.Round(sampleRate / loudnessStepsPerSecond)),
+		momentary: math.Inf(-1),
+		shortTerm: math.Inf(-1),
+	}
+	if l.stepLen < 1 {
+		l.stepLen = 1
+	}
+	for c := range l.filters {
+		l.filters[c] = newKWeighting(sampleRate)
+	}
+	return l
+}
+
+// Measure measures a block of the mix buffer
+func (l *LoudnessMeter) Measure(buf mixing.MixBuffer) {
+	for _, samp := range buf {
+		for c := 0; c < samp.Channels; c++ {
+			z := l.filters[c].process(float64(samp.StaticMatrix[c]))
+			l.stepPower += l.Weights[c] * z * z
+		}
+		l.stepPos++
+		if l.stepPos >= l.stepLen {
+			l.endStep()
+		}
+	}
+}
+
+// endStep completes a 100ms step, updating the momentary and short-term windows
+func (l *LoudnessMeter) endStep() {
+	l.steps[l.stepHead] = l.stepPower / float64(l.stepLen)
+	l.stepHead = (l.stepHead + 1) % len(l.steps)
+	if l.numSteps < len(l.steps) {
+		l.numSteps++
+	}
+	l.stepPower = 0
+	l.stepPos = 0
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.numSteps >= momentarySteps {
+		p := l.windowPower(momentarySteps)
+		l.blocks.add(p)
+		l.momentary = powerToLUFS(p)
+	}
+	if l.numSteps >= shortTermSteps {
+		p := l.windowPower(shortTermSteps)
+		l.shortTerms.add(p)
+		l.shortTerm = powerToLUFS(p)
+	}
+}
+
+// windowPower returns the mean power of the most recent `n` steps
+func (l *LoudnessMeter) windowPower(n int) float64 {
+	var sum float64
+	for i := 1; i <= n; i++ {
+		sum += l.steps[(l.stepHead-i+len(l.steps))%len(l.steps)]
+	}
+	return sum / float64(n)
+}
+
+// Momentary returns the loudness of the last 400ms in LUFS
+func (l *LoudnessMeter) Momentary() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.momentary
+}
+
+// ShortTerm returns the loudness of the last 3s in LUFS
+func (l *LoudnessMeter) ShortTerm() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.shortTerm
+}
+
+// Integrated returns the gated loudness in LUFS of everything measured since the last reset
+func (l *LoudnessMeter) Integrated() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.blocks.integrated()
+}
+
+// LoudnessRange returns the loudness range (LRA) in LU of everything measured since the last reset
+func (l *LoudnessMeter) LoudnessRange() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.shortTerms.loudnessRange()
+}
+
+// Snapshot returns all of the meter's readings
+func (l *LoudnessMeter) Snapshot() LoudnessLevels {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return LoudnessLevels{
+		Momentary:  l.momentary,
+		ShortTerm:  l.shortTerm,
+		Integrated: l.blocks.integrated(),
+		Range:      l.shortTerms.loudnessRange(),
+	}
+}
+
+func powerToLUFS(p float64) float64 {
+	if p <= 0 {
+		return math.Inf(-1)
+	}
+	return -0.691 + 10*math.Log10(p)
+}
+
+func lufsToPower(lufs float64) float64 {
+	return math.Pow(10, (lufs+0.691)/10)
+}
+
+// integrated returns the gated loudness in LUFS of the blocks in the histogram
+func (h *loudnessHistogram) integrated() float64 {
+	p, n := h.gatedMean(AbsoluteGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	p, n = h.gatedMean(powerToLUFS(p) + integratedRelativeGate)
+	if n == 0 {
+		return math.Inf(-1)
+	}
+	return powerToLUFS(p)
+}
+
+// loudnessRange returns the loudness range in LU of the short-term blocks in the histogram
+func (h *loudnessHistogram) loudnessRange() float64 {
+	p, n := h.gatedMean(AbsoluteGate)
+	if n == 0 {
+		return 0
+	}
+	gate := lufsToPower(powerToLUFS(p) + rangeRelativeGate)
+	firstBin := loudnessBins
+	n = 0
+	for bin, count := range h.counts {
+		if count != 0 && h.binPower(bin) > gate {
+			if bin < firstBin {
+				firstBin = bin
+			}
+			n += count
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	low := h.percentile(firstBin, n, rangeLowPercentile)
+	high := h.percentile(firstBin, n, rangeHighPercentile)
+	return high - low
+}
+
+// Process measures the mix buffer without changing it, so the meter can be used as an effect
+func (l *LoudnessMeter) Process(buf mixing.MixBuffer) {
+	l.Measure(buf)
+}
+
+// Reset clears the filters and all measurements
+func (l *LoudnessMeter) Reset() {
+	for c := range l.filters {
+		l.filters[c].reset()
+	}
+	l.steps = [shortTermSteps]float64{}
+	l.stepHead = 0
+	l.numSteps = 0
+	l.stepPos = 0
+	l.stepPower = 0
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.blocks.reset()
+	l.shortTerms.reset()
+	l.momentary = math.Inf(-1)
+	l.shortTerm = math.Inf(-1)
+}
+
+// Latency returns the number of samples the meter delays its output by
+func (l *LoudnessMeter) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the loudness meter
+func (l *LoudnessMeter) Parameters() []mixing.ParameterInfo {
+	return nil
+}
+
+// GetParameter returns the current value of a parameter
+func (l *LoudnessMeter) GetParameter(name string) (float64, error) {
+	return 0, fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, "loudness", name)
+}
+
+// SetParameter sets the value of a parameter
+func (l *LoudnessMeter) SetParameter(name string, value float64) error {
+	return fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, "loudness", name)
+}
@@ -0,0 +1,165 @@
+package metering
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+const loudnessTestRate = 48000
+
+// toneSegment is a 1kHz sine on every channel at a level in dBFS
+type toneSegment struct {
+	dBFS    float64
+	seconds float64
+}
+
+// measureTones runs a sequence of tone segments through a loudness meter in 1024-sample blocks
+func measureTones(l *LoudnessMeter, channels int, segments []toneSegment) {
+	buf := make(mixing.MixBuffer, 0, 1024)
+	var n int
+	flush := func() {
+		l.Measure(buf)
+		buf = buf[:0]
+	}
+	for _, seg := range segments {
+		amp := volume.FromDB(seg.dBFS)
+		frames := int(math.Round(seg.seconds * loudnessTestRate))
+		for i := 0; i < frames; i++ {
+			v := volume.Volume(amp * math.Sin(2*math.Pi*1000*float64(n)/loudnessTestRate))
+			samp := volume.Matrix{Channels: channels}
+			for c := 0; c < channels; c++ {
+				samp.StaticMatrix[c] = v
+			}
+			buf = append(buf, samp)
+			if len(buf) == cap(buf) {
+				flush()
+			}
+			n++
+		}
+	}
+	flush()
+}
+
+func expectLoudness(t *testing.T, what string, got float64, want float64, tolerance float64) {
+	t.Helper()
+	if math.IsNaN(got) || math.Abs(got-want) > tolerance {
+		t.Errorf("%s: got %v, want %v±%v", what, got, want, tolerance)
+	}
+}
+
+// TestLoudnessTech3341 runs the minimum requirement tests of EBU Tech 3341 that apply to stereo
+func TestLoudnessTech3341(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []toneSegment
+		level    float64
+		steady   bool
+	}{
+		{
+			name:     "case 1",
+			segments: []toneSegment{{-23, 20}},
+			level:    -23,
+			steady:   true,
+		},
+		{
+			name:     "case 2",
+			segments: []toneSegment{{-33, 20}},
+			level:    -33,
+			steady:   true,
+		},
+		{
+			name:     "case 3",
+			segments: []toneSegment{{-36, 10}, {-23, 60}, {-36, 10}},
+			level:    -23,
+		},
+		{
+			name:     "case 4",
+			segments: []toneSegment{{-72, 10}, {-36, 10}, {-23, 60}, {-36, 10}, {-72, 10}},
+			level:    -23,
+		},
+		{
+			name:     "case 5",
+			segments: []toneSegment{{-26, 20}, {-20, 20.1}, {-26, 20}},
+			level:    -23,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoudnessMeter(loudnessTestRate, 2)
+			measureTones(l, 2, tt.segments)
+			levels := l.Snapshot()
+			expectLoudness(t, "integrated", levels.Integrated, tt.level, 0.1)
+			if tt.steady {
+				expectLoudness(t, "momentary", levels.Momentary, tt.level, 0.1)
+				expectLoudness(t, "short-term", levels.ShortTerm, tt.level, 0.1)
+			}
+		})
+	}
+}
+
+// TestLoudnessRangeTech3342 runs the minimum requirement tests of EBU Tech 3342
+func TestLoudnessRangeTech3342(t *testing.T) {
+	tests := []struct {
+		name     string
+		segments []toneSegment
+		lra      float64
+	}{
+		{name: "case 1", segments: []toneSegment{{-20, 20}, {-30, 20}}, lra: 10},
+		{name: "case 2", segments: []toneSegment{{-20, 20}, {-15, 20}}, lra: 5},
+		{name: "case 3", segments: []toneSegment{{-40, 20}, {-20, 20}}, lra: 20},
+		{name: "case 4", segments: []toneSegment{{-50, 20}, {-35, 20}, {-20, 20}, {-35, 20}, {-50, 20}}, lra: 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLoudnessMeter(loudnessTestRate, 2)
+			measureTones(l, 2, tt.segments)
+			expectLoudness(t, "loudness range", l.LoudnessRange(), tt.lra, 1)
+		})
+	}
+}
+
+func TestLoudnessGating(t *testing.T) {
+	l := NewLoudnessMeter(loudnessTestRate, 2)
+	measureTones(l, 2, []toneSegment{{-80, 10}})
+	if got := l.Integrated(); !math.IsInf(got, -1) {
+		t.Errorf("integrated loudness below the absolute gate: got %v, want -Inf", got)
+	}
+	if got := l.LoudnessRange(); got != 0 {
+		t.Errorf("loudness range below the absolute gate: got %v, want 0", got)
+	}
+
+	// quiet passages more than 10 LU down don't pull the integrated loudness down
+	measureTones(l, 2, []toneSegment{{-20, 10}, {-40, 30}, {-20, 10}})
+	expectLoudness(t, "integrated", l.Integrated(), -20, 0.1)
+}
+
+func TestLoudnessReset(t *testing.T) {
+	l := NewLoudnessMeter(loudnessTestRate, 2)
+	measureTones(l, 2, []toneSegment{{-10, 5}})
+	l.Reset()
+	levels := l.Snapshot()
+	for name, v := range map[string]float64{
+		"momentary":  levels.Momentary,
+		"short-term": levels.ShortTerm,
+		"integrated": levels.Integrated,
+	} {
+		if !math.IsInf(v, -1) {
+			t.Errorf("%s after reset: got %v, want -Inf", name, v)
+		}
+	}
+	measureTones(l, 2, []toneSegment{{-30, 5}})
+	expectLoudness(t, "integrated after reset", l.Integrated(), -30, 0.1)
+}
+
+func TestLoudnessSurroundWeighting(t *testing.T) {
+	// a tone on every channel of a quad layout is louder than stereo by the two weighted surrounds
+	l := NewLoudnessMeter(loudnessTestRate, 4)
+	measureTones(l, 4, []toneSegment{{-30, 5}})
+	want := -30 + 10*math.Log10((2+2*1.41)/2)
+	expectLoudness(t, "integrated", l.Integrated(), want, 0.1)
+}
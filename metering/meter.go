@@ -0,0 +1,217 @@
+package metering
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// MaxChannels is the largest number of channels a meter can measure
+const MaxChannels = volume.MaxChannels
+
+// Ballistics describe how quickly a meter reading rises and falls
+type Ballistics struct {
+	// Attack is the time in milliseconds a reading takes to rise. Zero rises instantly
+	Attack float64
+	// Release is the time in milliseconds a reading takes to fall
+	Release float64
+	// RMSWindow is the time in milliseconds over which the RMS level is averaged
+	RMSWindow float64
+}
+
+// DefaultBallistics are typical peak programme meter ballistics
+var DefaultBallistics = Ballistics{
+	Attack:    0,
+	Release:   1500,
+	RMSWindow: 300,
+}
+
+// Levels is a snapshot of a meter's readings. All levels are linear amplitudes
+type Levels struct {
+	Channels int
+	Peak     [volume.MaxChannels]float64
+	RMS      [volume.MaxChannels]float64
+	TruePeak [volume.MaxChannels]float64
+	// PeakHold is the highest true-peak level measured since the meter was last reset
+	PeakHold [volume.MaxChannels]float64
+}
+
+// PeakDB returns the peak level of a channel in dBFS
+func (l Levels) PeakDB(ch int) float64 {
+	return volume.ToDB(l.Peak[ch])
+}
+
+// RMSDB returns the RMS level of a channel in dBFS
+func (l Levels) RMSDB(ch int) float64 {
+	return volume.ToDB(l.RMS[ch])
+}
+
+// TruePeakDB returns the true-peak level of a channel in dBTP
+func (l Levels) TruePeakDB(ch int) float64 {
+	return volume.ToDB(l.TruePeak[ch])
+}
+
+// ToDB converts a linear amplitude to decibels
+func ToDB(v float64) float64 {
+	return volume.ToDB(v)
+}
+
+// Meter measures peak, RMS and true-peak levels of a multichannel signal.
+// Measuring is done on the mixing goroutine, while Snapshot may be called from any goroutine.
+// A Meter is also a mixing.Effect that leaves the audio untouched, so it can be attached to
+// the effect chain of channel data, a bus, or the master mix.
+type Meter struct {
+	Ballistics Ballistics
+
+	sampleRate float64
+	levels     Levels
+	truePeak   [volume.MaxChannels]truePeakDetector
+	meanSquare [volume.MaxChannels]float64
+
+	mu       sync.Mutex
+	snapshot Levels
+}
+
+// NewMeter returns a meter for the sample rate provided using the default ballistics
+func NewMeter(sampleRate float64) *Meter {
+	return &Meter{
+		Ballistics: DefaultBallistics,
+		sampleRate: sampleRate,
+	}
+}
+
+// Snapshot returns the readings as of the end of the last measured block
+func (m *Meter) Snapshot() Levels {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.snapshot
+}
+
+// Measure measures a block of the mix buffer
+func (m *Meter) Measure(buf mixing.MixBuffer) {
+	c := m.coefficients()
+	for _, samp := range buf {
+		m.measureSample(samp, c)
+	}
+	m.publish()
+}
+
+// MeasureChannelData measures a channel's data as it would be mixed by the pan mixer provided
+func (m *Meter) MeasureChannelData(cd mixing.ChannelData, panmixer mixing.PanMixer) {
+	c := m.coefficients()
+	for _, d := range cd {
+		volMtx := panmixer.GetMixingMatrix(d.Pan).Apply(d.Volume)
+		for _, samp := range d.Data {
+			if samp.Channels == 0 {
+				samp = volume.Matrix{Channels: volMtx.Channels}
+			}
+			m.measureSample(volMtx.ApplyToMatrix(samp), c)
+		}
+	}
+	m.publish()
+}
+
+type meterCoefficients struct {
+	attack, release, window float64
+}
+
+func (m *Meter) coefficients() meterCoefficients {
+	return meterCoefficients{
+		attack:  1 - volume.SmoothingCoeff(m.Ballistics.Attack, m.sampleRate),
+		release: 1 - volume.SmoothingCoeff(m.Ballistics.Release, m.sampleRate),
+		window:  1 - volume.SmoothingCoeff(m.Ballistics.RMSWindow, m.sampleRate),
+	}
+}
+
+func (m *Meter) measureSample(samp volume.Matrix, c meterCoefficients) {
+	if samp.Channels > m.levels.Channels {
+		m.levels.Channels = samp.Channels
+	}
+	for ch := 0; ch < samp.Channels; ch++ {
+		x := float64(samp.StaticMatrix[ch])
+		m.levels.Peak[ch] = ballistic(m.levels.Peak[ch], math.Abs(x), c)
+
+		m.meanSquare[ch] += (x*x - m.meanSquare[ch]) * c.window
+		m.levels.RMS[ch] = math.Sqrt(m.meanSquare[ch])
+
+		tp := m.truePeak[ch].process(x)
+		m.levels.TruePeak[ch] = ballistic(m.levels.TruePeak[ch], tp, c)
+		if tp > m.levels.PeakHold[ch] {
+			m.levels.PeakHold[ch] = tp
+		}
+	}
+}
+
+func (m *Meter) publish() {
+	m.mu.Lock()
+	m.snapshot = m.levels
+	m.mu.Unlock()
+}
+
+// ballistic moves a reading toward a new level using the attack or release rate
+func ballistic(reading float64, level float64, c meterCoefficients) float64 {
+	if level > reading {
+		return reading + (level-reading)*c.attack
+	}
+	return reading + (level-reading)*c.release
+}
+
+// Process measures the mix buffer without changing it, so the meter can be used as an effect
+func (m *Meter) Process(buf mixing.MixBuffer) {
+	m.Measure(buf)
+}
+
+// Reset clears the readings and the peak hold
+func (m *Meter) Reset() {
+	m.levels = Levels{}
+	for ch := range m.truePeak {
+		m.truePeak[ch].reset()
+		m.meanSquare[ch] = 0
+	}
+	m.publish()
+}
+
+// Latency returns the number of samples the meter delays its output by
+func (m *Meter) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the meter
+func (m *Meter) Parameters() []mixing.ParameterInfo {
+	return []mixing.ParameterInfo{
+		{Name: "attack", Min: 0, Max: 5000, Default: DefaultBallistics.Attack},
+		{Name: "release", Min: 0, Max: 10000, Default: DefaultBallistics.Release},
+		{Name: "rmswindow", Min: 1, Max: 10000, Default: DefaultBallistics.RMSWindow},
+	}
+}
+
+// GetParameter returns the current value of a parameter
+func (m *Meter) GetParameter(name string) (float64, error) {
+	switch name {
+	case "attack":
+		return m.Ballistics.Attack, nil
+	case "release":
+		return m.Ballistics.Release, nil
+	case "rmswindow":
+		return m.Ballistics.RMSWindow, nil
+	}
+	return 0, fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, "meter", name)
+}
+
+// SetParameter sets the value of a parameter
+func (m *Meter) SetParameter(name string, value float64) error {
+	switch name {
+	case "attack":
+		m.Ballistics.Attack = value
+	case "release":
+		m.Ballistics.Release = value
+	case "rmswindow":
+		m.Ballistics.RMSWindow = value
+	default:
+		return fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, "meter", name)
+	}
+	return nil
+}
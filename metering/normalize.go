@@ -0,0 +1,218 @@
+package metering
+
+import (
+	"io"
+	"math"
+
+	"github.com/gotracker/gomixing/effect"
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+const (
+	// DefaultTargetLoudness is the default integrated loudness target in LUFS
+	DefaultTargetLoudness = -14.0
+	// DefaultTruePeakCeiling is the default true-peak ceiling in dBTP
+	DefaultTruePeakCeiling = -1.0
+	// DefaultNormalizationLookahead is the default look-ahead in milliseconds of the normalization limiter
+	DefaultNormalizationLookahead = 5.0
+
+	// normalizationMargin is the headroom in dB left under the ceiling when trimming limited output,
+	// covering the rounding of the output samples
+	normalizationMargin = 0.01
+)
+
+// RenderFunc renders a complete song from the beginning, passing each block of the mix to `emit`.
+// It is called once per normalization pass, so it must produce the same output each time.
+type RenderFunc func(emit func(buf mixing.MixBuffer) error) error
+
+// Normalization is a two-pass loudness normalizer for offline renders. The first pass
+// measures the integrated loudness and true peak of the render, and the second pass
+// applies the gain needed to reach the target loudness without exceeding the true-peak ceiling.
+// When the limiter is needed, the first pass is followed by a check pass over the limited output,
+// since limiting can still leave peaks between samples over the ceiling.
+type Normalization struct {
+	// TargetLoudness is the integrated loudness to normalize to, in LUFS
+	TargetLoudness float64
+	// TruePeakCeiling is the highest allowed true-peak level, in dBTP
+	TruePeakCeiling float64
+	// Limit enables a look-ahead limiter when the gain would take peaks over the ceiling.
+	// Without it, the gain is reduced instead. Either way, the result may fall short of the target loudness
+	Limit bool
+	// Lookahead is the look-ahead time in milliseconds of the limiter
+	Lookahead float64
+
+	sampleRate float64
+	channels   int
+}
+
+// NormalizationResult describes the measurement and gain of a normalization
+type NormalizationResult struct {
+	// Integrated is the measured integrated loudness in LUFS
+	Integrated float64
+	// TruePeak is the measured true-peak level in dBTP
+	TruePeak float64
+	// Gain is the gain in dB applied in the second pass
+	Gain float64
+	// Limited is set when the limiter is needed to keep peaks under the ceiling
+	Limited bool
+	// Trim is the gain in dB applied after the limiter to bring any remaining true peaks under the ceiling
+	Trim float64
+
+	// overshoot is how far in dB the true peak exceeds the sample peak
+	overshoot float64
+}
+
+// NewNormalization returns a normalizer for renders of the sample rate and channel count provided
+func NewNormalization(sampleRate float64, channels int) *Normalization {
+	return &Normalization{
+		TargetLoudness:  DefaultTargetLoudness,
+		TruePeakCeiling: DefaultTruePeakCeiling,
+		Limit:           true,
+		Lookahead:       DefaultNormalizationLookahead,
+		sampleRate:      sampleRate,
+		channels:        channels,
+	}
+}
+
+// Normalize runs both passes, writing the normalized render to `out` in the format provided
+func (n *Normalization) Normalize(render RenderFunc, out io.Writer, formatter sampling.Formatter) (NormalizationResult, error) {
+	result, err := n.Measure(render)
+	if err != nil {
+		return result, err
+	}
+	return result, n.Apply(render, result, out, formatter)
+}
+
+// Measure runs the first pass, measuring the render and working out the gain to apply.
+// When the limiter is needed, it also runs the check pass
+func (n *Normalization) Measure(render RenderFunc) (NormalizationResult, error) {
+	loudness := NewLoudnessMeter(n.sampleRate, n.channels)
+	var tp [volume.MaxChannels]truePeakDetector
+	peak, samplePeak := 0.0, 0.0
+	err := render(func(buf mixing.MixBuffer) error {
+		loudness.Measure(buf)
+		for _, samp := range buf {
+			for c := 0; c < samp.Channels; c++ {
+				x := float64(samp.StaticMatrix[c])
+				peak = math.Max(peak, tp[c].process(x))
+				samplePeak = math.Max(samplePeak, math.Abs(x))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return NormalizationResult{}, err
+	}
+
+	result := NormalizationResult{
+		Integrated: loudness.Integrated(),
+		TruePeak:   volume.ToDB(peak),
+	}
+	if peak > samplePeak && samplePeak > 0 {
+		result.overshoot = volume.ToDB(peak) - volume.ToDB(samplePeak)
+	}
+	if math.IsInf(result.Integrated, -1) {
+		// silence can't be normalized
+		return result, nil
+	}
+	result.Gain = n.TargetLoudness - result.Integrated
+	if result.TruePeak+result.Gain > n.TruePeakCeiling {
+		if n.Limit {
+			result.Limited = true
+		} else {
+			result.Gain = n.TruePeakCeiling - result.TruePeak
+		}
+	}
+	if result.Limited {
+		return n.check(render, result)
+	}
+	return result, nil
+}
+
+// check measures the true peak of the limited output and sets the trim needed to keep it under the ceiling
+func (n *Normalization) check(render RenderFunc, result NormalizationResult) (NormalizationResult, error) {
+	var tp [volume.MaxChannels]truePeakDetector
+	peak := 0.0
+	err := n.process(render, result, func(buf mixing.MixBuffer) error {
+		for _, samp := range buf {
+			for c := 0; c < samp.Channels; c++ {
+				peak = math.Max(peak, tp[c].process(float64(samp.StaticMatrix[c])))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	// the output ends after its last sample, so flush the interpolation filters
+	for c := 0; c < n.channels && c < len(tp); c++ {
+		for i := 0; i < truePeakTapsPerPhase; i++ {
+			peak = math.Max(peak, tp[c].process(0))
+		}
+	}
+	if excess := volume.ToDB(peak) - n.TruePeakCeiling; excess > 0 {
+		result.Trim = -(excess + normalizationMargin)
+	}
+	return result, nil
+}
+
+// Apply runs the second pass, applying the gain of a measurement to the render and
+// writing the result to `out` in the format provided
+func (n *Normalization) Apply(render RenderFunc, result NormalizationResult, out io.Writer, formatter sampling.Formatter) error {
+	return n.process(render, result, func(buf mixing.MixBuffer) error {
+		_, err := out.Write(buf.ToRenderData(len(buf), n.channels, 1, formatter))
+		return err
+	})
+}
+
+// process renders with the gain, limiter and trim of a measurement applied, passing the output to `emit`
+func (n *Normalization) process(render RenderFunc, result NormalizationResult, emit func(buf mixing.MixBuffer) error) error {
+	gain := volume.Volume(volume.FromDB(result.Gain))
+	trim := volume.Volume(volume.FromDB(result.Trim))
+
+	var limiter *effect.Limiter
+	skip := 0
+	if result.Limited {
+		limiter = effect.NewLimiter(n.sampleRate, n.Lookahead)
+		// the limiter works on sample peaks, so leave room for the overshoot between samples
+		limiter.Ceiling = n.TruePeakCeiling - result.overshoot
+		skip = limiter.Latency()
+	}
+
+	write := func(buf mixing.MixBuffer) error {
+		if limiter != nil {
+			limiter.Process(buf)
+			if skip > 0 {
+				// drop the limiter's look-ahead delay from the start of the render
+				drop := skip
+				if drop > len(buf) {
+					drop = len(buf)
+				}
+				buf = buf[drop:]
+				skip -= drop
+			}
+			for i, samp := range buf {
+				buf[i] = samp.Apply(trim)
+			}
+		}
+		return emit(buf)
+	}
+
+	err := render(func(buf mixing.MixBuffer) error {
+		scaled := make(mixing.MixBuffer, len(buf))
+		for i, samp := range buf {
+			scaled[i] = samp.Apply(gain)
+		}
+		return write(scaled)
+	})
+	if err != nil || limiter == nil {
+		return err
+	}
+
+	// flush the end of the render out of the limiter's look-ahead delay
+	tail := make(mixing.MixBuffer, limiter.Latency())
+	tail.SetChannels(n.channels)
+	return write(tail)
+}
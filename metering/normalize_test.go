@@ -0,0 +1,127 @@
+package metering
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+const normalizeTestRate = 48000
+
+// burstRender renders a stereo signal with loud transients and a lot of energy near the Nyquist
+// frequency, where limiting creates the largest peaks between samples
+func burstRender(seconds float64) RenderFunc {
+	frames := int(seconds * normalizeTestRate)
+	return func(emit func(buf mixing.MixBuffer) error) error {
+		for pos := 0; pos < frames; pos += 1000 {
+			buf := make(mixing.MixBuffer, 1000)
+			for i := range buf {
+				n := pos + i
+				env := 0.1
+				if (n/4800)%4 == 0 {
+					env = 0.5
+				}
+				hi := math.Sin(2*math.Pi*0.25*float64(n) + math.Pi/4)
+				lo := math.Sin(2 * math.Pi * 440 * float64(n) / normalizeTestRate)
+				buf[i] = volume.Matrix{
+					StaticMatrix: volume.StaticMatrix{
+						volume.Volume(env * (0.6*hi + 0.4*lo)),
+						volume.Volume(env * (0.4*hi - 0.6*lo)),
+					},
+					Channels: 2,
+				}
+			}
+			if err := emit(buf); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// decodeRender splits float render data into per-channel signals
+func decodeRender(t *testing.T, data []byte, channels int) [][]float64 {
+	t.Helper()
+	formatter := sampling.GetFormatter(sampling.Format32BitLEFloat)
+	out := make([][]float64, channels)
+	for ofs := 0; ofs+formatter.Size() <= len(data); ofs += formatter.Size() {
+		v, err := formatter.ReadAt(data, int64(ofs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := (ofs / formatter.Size()) % channels
+		out[c] = append(out[c], float64(v))
+	}
+	return out
+}
+
+func normalizeAndMeasure(t *testing.T, n *Normalization, render RenderFunc) (NormalizationResult, float64, float64) {
+	t.Helper()
+	var out bytes.Buffer
+	result, err := n.Normalize(render, &out, sampling.GetFormatter(sampling.Format32BitLEFloat))
+	if err != nil {
+		t.Fatal(err)
+	}
+	channels := decodeRender(t, out.Bytes(), 2)
+
+	var peak float64
+	for _, ch := range channels {
+		peak = math.Max(peak, truePeak(ch))
+	}
+	loudness := NewLoudnessMeter(normalizeTestRate, 2)
+	buf := make(mixing.MixBuffer, len(channels[0]))
+	for i := range buf {
+		buf[i] = volume.Matrix{
+			StaticMatrix: volume.StaticMatrix{volume.Volume(channels[0][i]), volume.Volume(channels[1][i])},
+			Channels:     2,
+		}
+	}
+	loudness.Measure(buf)
+	return result, volume.ToDB(peak), loudness.Integrated()
+}
+
+func TestNormalizationCeilingWithLimiter(t *testing.T) {
+	for _, target := range []float64{-7, -5, -3} {
+		n := NewNormalization(normalizeTestRate, 2)
+		n.TargetLoudness = target
+		result, peak, _ := normalizeAndMeasure(t, n, burstRender(5))
+		if !result.Limited {
+			t.Fatalf("target %v: expected the limiter to be needed", target)
+		}
+		if peak > n.TruePeakCeiling {
+			t.Errorf("target %v: output true peak %.4f dBTP exceeds the ceiling of %v dBTP", target, peak, n.TruePeakCeiling)
+		}
+	}
+}
+
+func TestNormalizationCeilingWithoutLimiter(t *testing.T) {
+	n := NewNormalization(normalizeTestRate, 2)
+	n.TargetLoudness = -6
+	n.Limit = false
+	result, peak, _ := normalizeAndMeasure(t, n, burstRender(5))
+	if result.Limited {
+		t.Fatal("limiter used with Limit disabled")
+	}
+	if peak > n.TruePeakCeiling+1e-3 {
+		t.Errorf("output true peak %.4f dBTP exceeds the ceiling of %v dBTP", peak, n.TruePeakCeiling)
+	}
+}
+
+func TestNormalizationReachesTarget(t *testing.T) {
+	n := NewNormalization(normalizeTestRate, 2)
+	n.TargetLoudness = -30
+	result, peak, integrated := normalizeAndMeasure(t, n, burstRender(5))
+	if result.Limited || result.Trim != 0 {
+		t.Errorf("unexpected limiting: %+v", result)
+	}
+	if math.Abs(integrated-n.TargetLoudness) > 0.1 {
+		t.Errorf("integrated loudness %v, want %v", integrated, n.TargetLoudness)
+	}
+	if peak > n.TruePeakCeiling {
+		t.Errorf("output true peak %.4f dBTP exceeds the ceiling", peak)
+	}
+}
@@ -0,0 +1,72 @@
+package metering
+
+import "math"
+
+const (
+	// truePeakOversampling is the oversampling factor used for true-peak measurement
+	truePeakOversampling = 4
+	// truePeakTapsPerPhase is the length of each polyphase branch of the interpolation filter
+	truePeakTapsPerPhase = 12
+)
+
+// truePeakFilter holds the polyphase branches of a windowed-sinc 4x interpolation filter
+var truePeakFilter = makeTruePeakFilter()
+
+func makeTruePeakFilter() [truePeakOversampling][truePeakTapsPerPhase]float64 {
+	var phases [truePeakOversampling][truePeakTapsPerPhase]float64
+	// an odd number of taps centers the filter on a tap, so one branch passes the original
+	// samples through unchanged and the interpolated peak can never read below the sample peak
+	const taps = truePeakOversampling*truePeakTapsPerPhase - 1
+	center := float64(taps-1) / 2
+	for n := 0; n < taps; n++ {
+		t := (float64(n) - center) / truePeakOversampling
+		sinc := 1.0
+		if t != 0 {
+			sinc = math.Sin(math.Pi*t) / (math.Pi * t)
+		}
+		// Blackman window
+		w := 0.42 - 0.5*math.Cos(2*math.Pi*float64(n)/float64(taps-1)) + 0.08*math.Cos(4*math.Pi*float64(n)/float64(taps-1))
+		phases[n%truePeakOversampling][n/truePeakOversampling] = sinc * w
+	}
+	// normalize each branch to unity gain at DC
+	for p := range phases {
+		var sum float64
+		for _, h := range phases[p] {
+			sum += h
+		}
+		for k := range phases[p] {
+			phases[p][k] /= sum
+		}
+	}
+	return phases
+}
+
+// truePeakDetector estimates the peak level between samples by 4x oversampling
+type truePeakDetector struct {
+	history [truePeakTapsPerPhase]float64
+	pos     int
+}
+
+// process adds a sample and returns the largest absolute value of the interpolated points
+func (d *truePeakDetector) process(x float64) float64 {
+	d.pos--
+	if d.pos < 0 {
+		d.pos = truePeakTapsPerPhase - 1
+	}
+	d.history[d.pos] = x
+
+	peak := math.Abs(x)
+	for p := range truePeakFilter {
+		var y float64
+		for k, h := range truePeakFilter[p] {
+			y += h * d.history[(d.pos+k)%truePeakTapsPerPhase]
+		}
+		peak = math.Max(peak, math.Abs(y))
+	}
+	return peak
+}
+
+func (d *truePeakDetector) reset() {
+	d.history = [truePeakTapsPerPhase]float64{}
+	d.pos = 0
+}
@@ -0,0 +1,67 @@
+package metering
+
+import (
+	"math"
+	"testing"
+)
+
+func truePeak(signal []float64) float64 {
+	var d truePeakDetector
+	var peak float64
+	for _, x := range signal {
+		peak = math.Max(peak, d.process(x))
+	}
+	// flush the interpolation filter
+	for i := 0; i < truePeakTapsPerPhase; i++ {
+		peak = math.Max(peak, d.process(0))
+	}
+	return peak
+}
+
+func TestTruePeakImpulse(t *testing.T) {
+	for _, amp := range []float64{1, -0.5} {
+		signal := make([]float64, 64)
+		signal[10] = amp
+		if got, want := truePeak(signal), math.Abs(amp); math.Abs(got-want) > 1e-9 {
+			t.Errorf("impulse of %v: got true peak %v, want %v", amp, got, want)
+		}
+	}
+}
+
+func TestTruePeakNeverBelowSamplePeak(t *testing.T) {
+	signal := make([]float64, 4096)
+	var seed uint32 = 1
+	var samplePeak float64
+	for i := range signal {
+		seed = seed*1664525 + 1013904223
+		signal[i] = float64(int32(seed)) / 2147483648.0
+		samplePeak = math.Max(samplePeak, math.Abs(signal[i]))
+	}
+	if got := truePeak(signal); got < samplePeak {
+		t.Errorf("true peak %v is below the sample peak %v", got, samplePeak)
+	}
+}
+
+func TestTruePeakInterSample(t *testing.T) {
+	// a quarter of the sample rate, phased so that every sample falls halfway between peaks
+	tests := []struct {
+		freq  float64
+		phase float64
+	}{
+		{freq: 0.25, phase: math.Pi / 4},
+		{freq: 1000.0 / 48000, phase: 0.3},
+		{freq: 0.1, phase: 0.5},
+	}
+	for _, tt := range tests {
+		signal := make([]float64, 4800)
+		var samplePeak float64
+		for i := range signal {
+			signal[i] = math.Sin(2*math.Pi*tt.freq*float64(i) + tt.phase)
+			samplePeak = math.Max(samplePeak, math.Abs(signal[i]))
+		}
+		got := truePeak(signal)
+		if db := 20 * math.Log10(got); math.Abs(db) > 0.2 {
+			t.Errorf("%v cycles per sample: true peak %v (%.3f dBTP), want 0 dBTP; sample peak %v", tt.freq, got, db, samplePeak)
+		}
+	}
+}
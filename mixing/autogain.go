@@ -0,0 +1,130 @@
+package mixing
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+// AutoGainMode is the level measurement an AutoGain tracks
+type AutoGainMode uint8
+
+const (
+	// AutoGainPeak tracks the peak level of the mix
+	AutoGainPeak = AutoGainMode(iota)
+	// AutoGainRMS tracks the RMS level of the mix
+	AutoGainRMS
+)
+
+const (
+	// DefaultAutoGainTarget is the default output level an AutoGain aims for
+	DefaultAutoGainTarget = volume.Volume(0.7)
+	// DefaultAutoGainMaxGain is the default limit on the gain, which lets quiet mixes be raised
+	// by up to 18dB. The gain is applied on top of the mixer volume, so a limit of 1 could only lower it
+	DefaultAutoGainMaxGain = volume.Volume(8)
+	// autoGainSilence is the level below which the gain is held instead of being raised
+	autoGainSilence = 1.0 / 4096.0
+)
+
+// AutoGain is an adaptive master gain that tracks the level of the summed mix and smoothly
+// moves the mixer volume toward a target level. Like the AGC of classic tracker players, it
+// pulls the gain down quickly when the mix gets loud and raises it slowly when the mix is quiet,
+// and it holds the gain during silence so that fade-ins don't start out too loud.
+type AutoGain struct {
+	Mode AutoGainMode
+	// Target is the output level to aim for
+	Target volume.Volume
+	// MinGain and MaxGain limit the gain applied. MaxGain should be above 1 for quiet mixes to be raised
+	MinGain volume.Volume
+	MaxGain volume.Volume
+	// Attack is the time in milliseconds over which the gain is lowered
+	Attack float64
+	// Release is the time in milliseconds over which the gain is raised
+	Release float64
+	// Window is the time in milliseconds over which the level is measured
+	Window float64
+
+	sampleRate float64
+	level      float64
+	gain       float64
+}
+
+// NewAutoGain returns an adaptive gain for the sample rate provided, starting at unity gain
+func NewAutoGain(sampleRate float64) *AutoGain {
+	return &AutoGain{
+		Mode:       AutoGainPeak,
+		Target:     DefaultAutoGainTarget,
+		MinGain:    1.0 / 64.0,
+		MaxGain:    DefaultAutoGainMaxGain,
+		Attack:     50,
+		Release:    3000,
+		Window:     300,
+		sampleRate: sampleRate,
+		gain:       1,
+	}
+}
+
+// Gain returns the gain currently applied
+func (a *AutoGain) Gain() volume.Volume {
+	return volume.Volume(a.gain)
+}
+
+// Level returns the currently measured level of the mix, before the gain is applied
+func (a *AutoGain) Level() volume.Volume {
+	if a.Mode == AutoGainRMS {
+		return volume.Volume(math.Sqrt(a.level))
+	}
+	return volume.Volume(a.level)
+}
+
+// Reset returns the gain to unity and clears the level measurement
+func (a *AutoGain) Reset() {
+	a.level = 0
+	a.gain = 1
+}
+
+// Process applies the mixer volume and the adaptive gain to the mix buffer in place
+func (a *AutoGain) Process(buf MixBuffer, channels int, mixerVolume volume.Volume) {
+	buf.SetChannels(channels)
+	window := volume.SmoothingCoeff(a.Window, a.sampleRate)
+	attack := volume.SmoothingCoeff(a.Attack, a.sampleRate)
+	release := volume.SmoothingCoeff(a.Release, a.sampleRate)
+	for i := range buf {
+		samp := buf[i].Apply(mixerVolume)
+
+		var peak, power float64
+		for c := 0; c < samp.Channels; c++ {
+			v := float64(samp.StaticMatrix[c])
+			peak = math.Max(peak, math.Abs(v))
+			power += v * v
+		}
+
+		var level float64
+		if a.Mode == AutoGainRMS {
+			if samp.Channels > 0 {
+				power /= float64(samp.Channels)
+			}
+			a.level += (power - a.level) * (1 - window)
+			level = math.Sqrt(a.level)
+		} else {
+			// instant attack, windowed decay
+			if peak > a.level {
+				a.level = peak
+			} else {
+				a.level += (peak - a.level) * (1 - window)
+			}
+			level = a.level
+		}
+
+		if level > autoGainSilence {
+			desired := float64(a.Target) / level
+			desired = math.Min(math.Max(desired, float64(a.MinGain)), float64(a.MaxGain))
+			if desired < a.gain {
+				a.gain += (desired - a.gain) * (1 - attack)
+			} else {
+				a.gain += (desired - a.gain) * (1 - release)
+			}
+		}
+		buf[i] = samp.Apply(volume.Volume(a.gain))
+	}
+}
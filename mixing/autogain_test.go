@@ -0,0 +1,53 @@
+package mixing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+func autoGainOutput(a *AutoGain, level volume.Volume, frames int) volume.Volume {
+	buf := make(MixBuffer, frames)
+	for i := range buf {
+		buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{level, level}, Channels: 2}
+	}
+	a.Process(buf, 2, 1)
+	return buf[len(buf)-1].StaticMatrix[0]
+}
+
+func TestAutoGainRaisesQuietMix(t *testing.T) {
+	const sampleRate = 44100
+	a := NewAutoGain(sampleRate)
+	out := autoGainOutput(a, 0.1, sampleRate*30)
+	if a.Gain() <= 1 {
+		t.Fatalf("gain %v was not raised for a quiet mix", a.Gain())
+	}
+	if math.Abs(float64(out-a.Target)) > 0.01 {
+		t.Errorf("output level %v, want %v", out, a.Target)
+	}
+}
+
+func TestAutoGainLowersLoudMix(t *testing.T) {
+	const sampleRate = 44100
+	a := NewAutoGain(sampleRate)
+	out := autoGainOutput(a, 2, sampleRate*2)
+	if math.Abs(float64(out-a.Target)) > 0.01 {
+		t.Errorf("output level %v, want %v", out, a.Target)
+	}
+}
+
+func TestAutoGainLimits(t *testing.T) {
+	const sampleRate = 44100
+	a := NewAutoGain(sampleRate)
+	autoGainOutput(a, 0.01, sampleRate*60)
+	if g := a.Gain(); math.Abs(float64(g-a.MaxGain)) > 1e-3 {
+		t.Errorf("gain %v, want it held at MaxGain %v", g, a.MaxGain)
+	}
+
+	a.Reset()
+	autoGainOutput(a, 0, sampleRate)
+	if g := a.Gain(); g != 1 {
+		t.Errorf("gain %v changed during silence", g)
+	}
+}
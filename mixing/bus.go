@@ -0,0 +1,303 @@
+package mixing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// MasterBusName is the name of the master bus, which all other buses eventually mix into
+const MasterBusName = "master"
+
+var (
+	// ErrBusCycle is returned when a routing change would cause a bus to feed into itself
+	ErrBusCycle = errors.New("bus routing cycle")
+	// ErrUnknownBus is returned when a bus name does not exist in the routing graph
+	ErrUnknownBus = errors.New("unknown bus")
+	// ErrDuplicateBus is returned when adding a bus with a name that's already in use
+	ErrDuplicateBus = errors.New("duplicate bus")
+)
+
+// Send is an auxiliary send from one bus to another
+type Send struct {
+	Bus    string
+	Volume volume.Volume
+	// PreFader takes the send before the source bus's volume and panning are applied
+	PreFader bool
+}
+
+// Bus is a named submix with its own volume and panning
+type Bus struct {
+	Name   string
+	Volume volume.Volume
+	Pan    panning.Position
+	// Effects is applied to the bus's mix before its sends and output
+	Effects EffectChain
+
+	output string
+	sends  []Send
+	buf    MixBuffer
+}
+
+// Output returns the name of the bus this bus mixes into
+func (b *Bus) Output() string {
+	return b.output
+}
+
+// Sends returns a copy of the auxiliary sends of the bus. Use BusGraph.AddSend to add new ones
+func (b *Bus) Sends() []Send {
+	return append([]Send(nil), b.sends...)
+}
+
+// BusGraph is a routing graph of buses that ends at a master bus
+type BusGraph struct {
+	buses  map[string]*Bus
+	routes map[int]string
+	order  []*Bus
+}
+
+// NewBusGraph returns a routing graph containing only the master bus
+func NewBusGraph() *BusGraph {
+	g := &BusGraph{
+		buses:  make(map[string]*Bus),
+		routes: make(map[int]string),
+	}
+	g.buses[MasterBusName] = &Bus{
+		Name:   MasterBusName,
+		Volume: 1,
+		Pan:    panning.CenterAhead,
+	}
+	return g
+}
+
+// Master returns the master bus
+func (g *BusGraph) Master() *Bus {
+	return g.buses[MasterBusName]
+}
+
+// Bus returns the bus with the name provided, or nil if there isn't one
+func (g *BusGraph) Bus(name string) *Bus {
+	return g.buses[name]
+}
+
+// AddBus adds a new bus with unity volume and center panning that outputs to the master bus
+func (g *BusGraph) AddBus(name string) (*Bus, error) {
+	if _, found := g.buses[name]; found {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicateBus, name)
+	}
+	b := &Bus{
+		Name:   name,
+		Volume: 1,
+		Pan:    panning.CenterAhead,
+		output: MasterBusName,
+	}
+	g.buses[name] = b
+	g.order = nil
+	return b, nil
+}
+
+// SetOutput routes the output of a bus into another bus
+func (g *BusGraph) SetOutput(name string, output string) error {
+	b, err := g.lookup(name)
+	if err != nil {
+		return err
+	}
+	if _, err := g.lookup(output); err != nil {
+		return err
+	}
+	if name == MasterBusName {
+		return fmt.Errorf("%w: %s cannot have an output", ErrBusCycle, MasterBusName)
+	}
+
+	prev := b.output
+	b.output = output
+	g.order = nil
+	if _, err := g.Order(); err != nil {
+		b.output = prev
+		g.order = nil
+		return err
+	}
+	return nil
+}
+
+// AddSend adds an auxiliary send from a bus to another bus
+func (g *BusGraph) AddSend(name string, send Send) error {
+	b, err := g.lookup(name)
+	if err != nil {
+		return err
+	}
+	if _, err := g.lookup(send.Bus); err != nil {
+		return err
+	}
+
+	b.sends = append(b.sends, send)
+	g.order = nil
+	if _, err := g.Order(); err != nil {
+		b.sends = b.sends[:len(b.sends)-1]
+		g.order = nil
+		return err
+	}
+	return nil
+}
+
+// Route routes a mixed channel's data into a bus. Unrouted channels go to the master bus
+func (g *BusGraph) Route(channel int, bus string) error {
+	if _, err := g.lookup(bus); err != nil {
+		return err
+	}
+	g.routes[channel] = bus
+	return nil
+}
+
+// ChannelBus returns the bus that a mixed channel is routed into
+func (g *BusGraph) ChannelBus(channel int) *Bus {
+	if name, ok := g.routes[channel]; ok {
+		return g.buses[name]
+	}
+	return g.Master()
+}
+
+// Order returns the buses in the order they must be mixed, so that every bus
+// is mixed before the buses it feeds. The master bus is always last.
+func (g *BusGraph) Order() ([]*Bus, error) {
+	if g.order != nil {
+		return g.order, nil
+	}
+
+	// Kahn's algorithm over the output and send edges
+	inDegree := make(map[string]int, len(g.buses))
+	for _, b := range g.buses {
+		for _, dest := range b.destinations() {
+			inDegree[dest]++
+		}
+	}
+	var ready []*Bus
+	for name, b := range g.buses {
+		if inDegree[name] == 0 {
+			ready = append(ready, b)
+		}
+	}
+	order := make([]*Bus, 0, len(g.buses))
+	for len(ready) > 0 {
+		b := ready[len(ready)-1]
+		ready = ready[:len(ready)-1]
+		order = append(order, b)
+		for _, dest := range b.destinations() {
+			inDegree[dest]--
+			if inDegree[dest] == 0 {
+				ready = append(ready, g.buses[dest])
+			}
+		}
+	}
+	if len(order) != len(g.buses) {
+		return nil, ErrBusCycle
+	}
+	g.order = order
+	return order, nil
+}
+
+func (g *BusGraph) lookup(name string) (*Bus, error) {
+	b, ok := g.buses[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownBus, name)
+	}
+	return b, nil
+}
+
+func (b *Bus) destinations() []string {
+	var dests []string
+	if b.output != "" {
+		dests = append(dests, b.output)
+	}
+	for _, s := range b.sends {
+		dests = append(dests, s.Bus)
+	}
+	return dests
+}
+
+// beginMix prepares the bus buffers for mixing `samples` samples
+func (g *BusGraph) beginMix(samples int) {
+	for _, b := range g.buses {
+		if cap(b.buf) >= samples {
+			b.buf = b.buf[:samples]
+			for i := range b.buf {
+				b.buf[i] = volume.Matrix{}
+			}
+		} else {
+			b.buf = make(MixBuffer, samples)
+		}
+	}
+}
+
+// busBuffer returns the mix buffer of the named bus, or the master bus if it doesn't exist
+func (g *BusGraph) busBuffer(name string) MixBuffer {
+	if b, ok := g.buses[name]; ok {
+		return b.buf
+	}
+	return g.Master().buf
+}
+
+// finishMix mixes all the buses down into the master bus and returns the result
+func (g *BusGraph) finishMix(panmixer PanMixer) MixBuffer {
+	order, err := g.Order()
+	if err != nil {
+		// unreachable: outputs and sends can only be changed through SetOutput and
+		// AddSend, which roll back any change that would create a cycle
+		panic(err)
+	}
+
+	channels := panmixer.NumChannels()
+	for _, b := range order {
+		if len(b.Effects) > 0 {
+			b.buf.SetChannels(channels)
+			b.Effects.Process(b.buf)
+		}
+		fader := balanceMatrix(panmixer, b.Pan).Apply(b.Volume)
+		for _, s := range b.sends {
+			sendMtx := fader.Apply(s.Volume)
+			if s.PreFader {
+				sendMtx = uniformMatrix(channels, s.Volume)
+			}
+			dest := g.buses[s.Bus].buf
+			dest.Add(0, &b.buf, sendMtx)
+		}
+		if b.output == "" {
+			continue
+		}
+		dest := g.buses[b.output].buf
+		dest.Add(0, &b.buf, fader)
+	}
+
+	master := g.Master()
+	out := make(MixBuffer, len(master.buf))
+	out.Add(0, &master.buf, balanceMatrix(panmixer, master.Pan).Apply(master.Volume))
+	return out
+}
+
+// balanceMatrix returns a panning matrix normalized so that center panning is unity gain
+func balanceMatrix(panmixer PanMixer, pan panning.Position) volume.Matrix {
+	mtx := panmixer.GetMixingMatrix(pan)
+	center := panmixer.GetMixingMatrix(panning.CenterAhead)
+	for c := 0; c < mtx.Channels; c++ {
+		if center.StaticMatrix[c] == 0 {
+			continue
+		}
+		v := mtx.StaticMatrix[c] / center.StaticMatrix[c]
+		if v > 1 {
+			v = 1
+		}
+		mtx.StaticMatrix[c] = v
+	}
+	return mtx
+}
+
+// uniformMatrix returns a matrix with every channel set to the same volume
+func uniformMatrix(channels int, vol volume.Volume) volume.Matrix {
+	mtx := volume.Matrix{Channels: channels}
+	for c := 0; c < channels; c++ {
+		mtx.StaticMatrix[c] = vol
+	}
+	return mtx
+}
@@ -0,0 +1,155 @@
+package mixing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gotracker/gomixing/volume"
+)
+
+func newTestBusGraph(t *testing.T, names ...string) *BusGraph {
+	t.Helper()
+	g := NewBusGraph()
+	for _, name := range names {
+		if _, err := g.AddBus(name); err != nil {
+			t.Fatalf("AddBus(%q): %v", name, err)
+		}
+	}
+	return g
+}
+
+func TestBusGraphRejectsCycles(t *testing.T) {
+	g := newTestBusGraph(t, "a", "b", "c")
+	if err := g.SetOutput("a", "b"); err != nil {
+		t.Fatalf("SetOutput(a, b): %v", err)
+	}
+	if err := g.SetOutput("b", "c"); err != nil {
+		t.Fatalf("SetOutput(b, c): %v", err)
+	}
+
+	if err := g.SetOutput("c", "a"); !errors.Is(err, ErrBusCycle) {
+		t.Errorf("SetOutput(c, a): got %v, want %v", err, ErrBusCycle)
+	}
+	if got := g.Bus("c").Output(); got != MasterBusName {
+		t.Errorf("output of c after rejected change: got %q, want %q", got, MasterBusName)
+	}
+
+	if err := g.AddSend("c", Send{Bus: "a", Volume: 1}); !errors.Is(err, ErrBusCycle) {
+		t.Errorf("AddSend(c, a): got %v, want %v", err, ErrBusCycle)
+	}
+	if got := len(g.Bus("c").Sends()); got != 0 {
+		t.Errorf("sends of c after rejected send: got %d, want 0", got)
+	}
+
+	if err := g.AddSend(MasterBusName, Send{Bus: "a", Volume: 1}); !errors.Is(err, ErrBusCycle) {
+		t.Errorf("AddSend(master, a): got %v, want %v", err, ErrBusCycle)
+	}
+	if err := g.SetOutput(MasterBusName, "a"); !errors.Is(err, ErrBusCycle) {
+		t.Errorf("SetOutput(master, a): got %v, want %v", err, ErrBusCycle)
+	}
+	if err := g.SetOutput("a", "missing"); !errors.Is(err, ErrUnknownBus) {
+		t.Errorf("SetOutput(a, missing): got %v, want %v", err, ErrUnknownBus)
+	}
+	if _, err := g.AddBus("a"); !errors.Is(err, ErrDuplicateBus) {
+		t.Errorf("AddBus(a) twice: got %v, want %v", err, ErrDuplicateBus)
+	}
+
+	// the graph still mixes after the rejected changes
+	if _, err := g.Order(); err != nil {
+		t.Errorf("Order after rejected changes: %v", err)
+	}
+}
+
+func TestBusGraphSendsCopy(t *testing.T) {
+	g := newTestBusGraph(t, "a", "b")
+	if err := g.AddSend("a", Send{Bus: "b", Volume: 1}); err != nil {
+		t.Fatalf("AddSend(a, b): %v", err)
+	}
+	sends := g.Bus("a").Sends()
+	sends[0].Bus = "a"
+	if got := g.Bus("a").Sends()[0].Bus; got != "b" {
+		t.Errorf("send destination after editing the copy: got %q, want %q", got, "b")
+	}
+}
+
+func TestBusGraphOrder(t *testing.T) {
+	g := newTestBusGraph(t, "a", "b", "c", "d", "fx")
+	routes := [][2]string{{"a", "b"}, {"b", "c"}, {"d", "c"}}
+	for _, r := range routes {
+		if err := g.SetOutput(r[0], r[1]); err != nil {
+			t.Fatalf("SetOutput(%s, %s): %v", r[0], r[1], err)
+		}
+	}
+	for _, src := range []string{"a", "d"} {
+		if err := g.AddSend(src, Send{Bus: "fx", Volume: 1}); err != nil {
+			t.Fatalf("AddSend(%s, fx): %v", src, err)
+		}
+	}
+
+	order, err := g.Order()
+	if err != nil {
+		t.Fatalf("Order: %v", err)
+	}
+	if len(order) != 6 {
+		t.Fatalf("order length: got %d, want 6", len(order))
+	}
+	if last := order[len(order)-1].Name; last != MasterBusName {
+		t.Errorf("last bus: got %q, want %q", last, MasterBusName)
+	}
+	index := make(map[string]int)
+	for i, b := range order {
+		index[b.Name] = i
+	}
+	for _, b := range order {
+		for _, dest := range b.destinations() {
+			if index[b.Name] >= index[dest] {
+				t.Errorf("bus %q is mixed at %d, after its destination %q at %d", b.Name, index[b.Name], dest, index[dest])
+			}
+		}
+	}
+}
+
+func TestBusSendLevels(t *testing.T) {
+	const samples = 4
+	panmixer := GetPanMixer(2)
+
+	tests := []struct {
+		name     string
+		preFader bool
+		want     volume.Volume
+	}{
+		{name: "post-fader", preFader: false, want: 0.8 * 0.5 * 0.25},
+		{name: "pre-fader", preFader: true, want: 0.8 * 0.25},
+	}
+	for _, tt := range tests {
+		g := newTestBusGraph(t, "src", "fx")
+		g.Bus("src").Volume = 0.5
+		if err := g.AddSend("src", Send{Bus: "fx", Volume: 0.25, PreFader: tt.preFader}); err != nil {
+			t.Fatalf("%s: AddSend: %v", tt.name, err)
+		}
+		g.Bus("fx").Volume = 0
+
+		g.beginMix(samples)
+		src := g.Bus("src").buf
+		for i := range src {
+			src[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{0.8, 0.8}, Channels: 2}
+		}
+		out := g.finishMix(panmixer)
+
+		for i, s := range g.Bus("fx").buf {
+			for c := 0; c < 2; c++ {
+				if got := s.StaticMatrix[c]; !volumeClose(got, tt.want) {
+					t.Errorf("%s: fx sample %d channel %d: got %v, want %v", tt.name, i, c, got, tt.want)
+				}
+			}
+		}
+		// fx is muted, so the master only receives src through its fader
+		for i, s := range out {
+			for c := 0; c < 2; c++ {
+				if got, want := s.StaticMatrix[c], volume.Volume(0.8*0.5); !volumeClose(got, want) {
+					t.Errorf("%s: master sample %d channel %d: got %v, want %v", tt.name, i, c, got, want)
+				}
+			}
+		}
+	}
+}
@@ -13,6 +13,8 @@ type Data struct {
 	Pos        int
 	SamplesLen int
 	Flush      func()
+	// Effects is applied to the data after it has been panned into the output channels
+	Effects EffectChain
 }
 
 // ChannelData is a single channel's data
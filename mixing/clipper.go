@@ -0,0 +1,120 @@
+package mixing
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/filter"
+	"github.com/gotracker/gomixing/volume"
+)
+
+const (
+	// clipperCutoff is the anti-aliasing filter cutoff, as a fraction of the original sample rate
+	clipperCutoff = 0.45
+	// clipperRate is the nominal original sample rate the filters are designed at
+	clipperRate = 1000.0
+	// Butterworth Q values for a fourth-order response made of two biquads
+	clipperQ1 = 0.5411961
+	clipperQ2 = 1.3065630
+	// Bessel Q values and section frequencies, relative to the -3dB point, for a fourth-order
+	// response made of two biquads. Its impulse response barely rings, so the decimation
+	// filter needs little headroom
+	clipperBesselQ1    = 0.5219
+	clipperBesselQ2    = 0.8055
+	clipperBesselFreq1 = 1.4192
+	clipperBesselFreq2 = 1.5912
+	// clipperResponseLength is the number of samples of the decimation filter's impulse
+	// response that are summed to find its peak gain, long after it has decayed away
+	clipperResponseLength = 1 << 12
+	// clipperMargin covers the rounding of the filter's intermediate values
+	clipperMargin = 1e-6
+)
+
+// Clipper is the output stage of a mixer, which applies the mixer volume and then keeps the
+// mix within the output range using a clipping mode. Oversampling the clipping stage reduces
+// the aliasing caused by the harmonics that clipping generates. The decimation filter can
+// overshoot after the clipping stage, so the oversampled signal is clipped to a ceiling just
+// below full scale that leaves room for the largest overshoot the filter can produce.
+type Clipper struct {
+	Mode volume.ClipMode
+
+	oversampling int
+	up, down     [2]filter.Biquad
+	ceiling      volume.Volume
+}
+
+// NewClipper returns an output clipping stage that oversamples by the factor provided.
+// Factors below 2 disable oversampling
+func NewClipper(mode volume.ClipMode, oversampling int) *Clipper {
+	c := &Clipper{
+		Mode:         mode,
+		oversampling: oversampling,
+	}
+	if oversampling > 1 {
+		rate := clipperRate * float64(oversampling)
+		cutoff := clipperCutoff * clipperRate
+		c.up[0].Coefficients = filter.LowPassCoefficients(rate, cutoff, clipperQ1)
+		c.up[1].Coefficients = filter.LowPassCoefficients(rate, cutoff, clipperQ2)
+		c.down[0].Coefficients = filter.LowPassCoefficients(rate, cutoff*clipperBesselFreq1, clipperBesselQ1)
+		c.down[1].Coefficients = filter.LowPassCoefficients(rate, cutoff*clipperBesselFreq2, clipperBesselQ2)
+		c.ceiling = volume.Volume((1 - clipperMargin) / peakGain(c.down))
+	}
+	return c
+}
+
+// peakGain returns the sum of the absolute values of the impulse response of a chain of
+// biquads, which is the largest gain the chain can apply to any signal within -1.0 to 1.0
+func peakGain(chain [2]filter.Biquad) float64 {
+	for i := range chain {
+		chain[i].Reset()
+	}
+	var sum float64
+	in := volume.Matrix{StaticMatrix: volume.StaticMatrix{1}, Channels: 1}
+	for n := 0; n < clipperResponseLength; n++ {
+		out := chain[1].Filter(chain[0].Filter(in))
+		sum += math.Abs(float64(out.StaticMatrix[0]))
+		in.StaticMatrix[0] = 0
+	}
+	return sum
+}
+
+// Oversampling returns the oversampling factor of the clipping stage
+func (c *Clipper) Oversampling() int {
+	return c.oversampling
+}
+
+// Process applies the mixer volume to the mix buffer and clips it in place
+func (c *Clipper) Process(buf MixBuffer, channels int, mixerVolume volume.Volume) {
+	buf.SetChannels(channels)
+	if c.oversampling <= 1 {
+		for i := range buf {
+			buf[i] = buf[i].Apply(mixerVolume).Clip(c.Mode)
+		}
+		return
+	}
+
+	// the clipping curve is scaled to saturate at the ceiling rather than at full scale
+	gain := mixerVolume * volume.Volume(c.oversampling) / c.ceiling
+	silence := volume.Matrix{Channels: channels}
+	for i := range buf {
+		for k := 0; k < c.oversampling; k++ {
+			// zero-stuff, interpolate, clip, then filter before decimating
+			in := silence
+			if k == 0 {
+				in = buf[i].Apply(gain)
+			}
+			up := c.up[1].Filter(c.up[0].Filter(in))
+			down := c.down[1].Filter(c.down[0].Filter(up.Clip(c.Mode).Apply(c.ceiling)))
+			if k == 0 {
+				buf[i] = down
+			}
+		}
+	}
+}
+
+// Reset clears the oversampling filter histories
+func (c *Clipper) Reset() {
+	for i := range c.up {
+		c.up[i].Reset()
+		c.down[i].Reset()
+	}
+}
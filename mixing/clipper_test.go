@@ -0,0 +1,99 @@
+package mixing
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/fft"
+	"github.com/gotracker/gomixing/volume"
+)
+
+func TestClipperStaysInRange(t *testing.T) {
+	modes := []volume.ClipMode{volume.ClipHard, volume.ClipTanh, volume.ClipCubic, volume.ClipPolynomial}
+	for _, mode := range modes {
+		for _, oversampling := range []int{1, 2, 4, 8} {
+			c := NewClipper(mode, oversampling)
+			buf := make(MixBuffer, 4096)
+			for i := range buf {
+				// a loud square wave rings the most after the decimation filter
+				v := volume.Volume(4)
+				if (i/50)&1 != 0 {
+					v = -4
+				}
+				buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{v, v * 0.5}, Channels: 2}
+			}
+			c.Process(buf, 2, 1)
+
+			var peak float64
+			for _, samp := range buf {
+				for ch := 0; ch < samp.Channels; ch++ {
+					peak = math.Max(peak, math.Abs(float64(samp.StaticMatrix[ch])))
+				}
+			}
+			if peak > 1 {
+				t.Errorf("mode %d oversampling %d: peak %v exceeds 1", mode, oversampling, peak)
+			}
+			// oversampled clipping saturates below full scale to leave room for the filter's overshoot
+			ceiling := 0.9
+			if oversampling > 1 {
+				ceiling = float64(c.ceiling) * 0.999
+			}
+			if peak < ceiling {
+				t.Errorf("mode %d oversampling %d: peak %v, expected the signal to reach the ceiling %v", mode, oversampling, peak, ceiling)
+			}
+		}
+	}
+}
+
+// aliasedEnergy returns the energy of the first channel of the mix buffer that lies away from
+// the harmonics of a sine at `freq` (as a fraction of the sample rate). Clipping the sine adds
+// harmonics, so anything else comes from harmonics above the Nyquist frequency folding back
+func aliasedEnergy(buf MixBuffer, freq float64) float64 {
+	const guard = 4 // bins either side of a harmonic that the Hann window spreads it over
+	n := len(buf)
+	data := make([]complex128, n)
+	for i, samp := range buf {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+		data[i] = complex(w*float64(samp.StaticMatrix[0]), 0)
+	}
+	fft.NewPlan(n).Forward(data)
+
+	var energy float64
+	for k := 1; k <= n/2; k++ {
+		nearest := math.Round(float64(k)/(freq*float64(n))) * freq * float64(n)
+		if math.Abs(float64(k)-nearest) > guard {
+			energy += real(data[k])*real(data[k]) + imag(data[k])*imag(data[k])
+		}
+	}
+	return energy
+}
+
+func TestClipperOversamplingReducesAliasing(t *testing.T) {
+	const (
+		n    = 2048
+		freq = 0.0421 // not a divisor of the sample rate, so aliases fall between the harmonics
+	)
+	render := func(mode volume.ClipMode, oversampling int, drive float64) MixBuffer {
+		c := NewClipper(mode, oversampling)
+		// settle the filters before measuring
+		buf := make(MixBuffer, 2*n)
+		for i := range buf {
+			v := volume.Volume(drive * math.Sin(2*math.Pi*freq*float64(i)))
+			buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{v}, Channels: 1}
+		}
+		c.Process(buf, 1, 1)
+		return buf[n:]
+	}
+
+	for _, drive := range []float64{1.5, 3} {
+		hard := aliasedEnergy(render(volume.ClipHard, 1, drive), freq)
+		for _, mode := range []volume.ClipMode{volume.ClipTanh, volume.ClipCubic, volume.ClipPolynomial} {
+			for _, oversampling := range []int{2, 4, 8} {
+				got := aliasedEnergy(render(mode, oversampling, drive), freq)
+				if got >= hard {
+					t.Errorf("drive %v mode %d oversampling %d: aliased energy %g, want less than hard clipping's %g", drive, mode, oversampling, got, hard)
+				}
+			}
+		}
+	}
+}
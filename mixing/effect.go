@@ -0,0 +1,57 @@
+package mixing
+
+import "errors"
+
+// ErrUnknownParameter is returned when getting or setting a parameter an effect doesn't have
+var ErrUnknownParameter = errors.New("unknown parameter")
+
+// ParameterInfo describes a parameter of an effect
+type ParameterInfo struct {
+	Name    string
+	Min     float64
+	Max     float64
+	Default float64
+}
+
+// Effect is a DSP process that is applied to a mix buffer in place.
+// Every entry of the buffer handed to Process has the same number of channels.
+type Effect interface {
+	// Process applies the effect to the mix buffer in place
+	Process(buf MixBuffer)
+	// Reset clears the internal state of the effect, such as delay lines and filter histories
+	Reset()
+	// Latency returns the number of samples the effect delays its output by
+	Latency() int
+	// Parameters returns the descriptions of the parameters the effect supports
+	Parameters() []ParameterInfo
+	// GetParameter returns the current value of a parameter
+	GetParameter(name string) (float64, error)
+	// SetParameter sets the value of a parameter
+	SetParameter(name string, value float64) error
+}
+
+// EffectChain is a series of effects applied one after the other
+type EffectChain []Effect
+
+// Process applies each effect of the chain to the mix buffer in place
+func (c EffectChain) Process(buf MixBuffer) {
+	for _, e := range c {
+		e.Process(buf)
+	}
+}
+
+// Reset resets each effect of the chain
+func (c EffectChain) Reset() {
+	for _, e := range c {
+		e.Reset()
+	}
+}
+
+// Latency returns the total latency of the chain in samples
+func (c EffectChain) Latency() int {
+	latency := 0
+	for _, e := range c {
+		latency += e.Latency()
+	}
+	return latency
+}
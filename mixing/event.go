@@ -0,0 +1,118 @@
+package mixing
+
+import (
+	"sort"
+
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Event is an action applied to a voice pool at an exact sample frame during rendering
+type Event interface {
+	Apply(p *VoicePool)
+}
+
+// EventFunc is a function that can be used as an Event
+type EventFunc func(p *VoicePool)
+
+// Apply calls the function
+func (f EventFunc) Apply(p *VoicePool) {
+	f(p)
+}
+
+// NoteOnEvent starts a voice on a channel
+type NoteOnEvent struct {
+	Channel int
+	Voice   *Voice
+	Action  NewNoteAction
+}
+
+// Apply starts the voice
+func (e NoteOnEvent) Apply(p *VoicePool) {
+	p.NoteOn(e.Channel, e.Voice, e.Action)
+}
+
+// NoteOffEvent releases the voice playing on a channel
+type NoteOffEvent struct {
+	Channel int
+}
+
+// Apply releases the voice
+func (e NoteOffEvent) Apply(p *VoicePool) {
+	p.NoteOff(e.Channel)
+}
+
+// NoteCutEvent cuts the voice playing on a channel
+type NoteCutEvent struct {
+	Channel int
+}
+
+// Apply cuts the voice
+func (e NoteCutEvent) Apply(p *VoicePool) {
+	if v := p.Get(e.Channel); v != nil {
+		v.Cut()
+	}
+}
+
+// VolumeEvent changes the volume of the voice playing on a channel
+type VolumeEvent struct {
+	Channel int
+	Volume  volume.Volume
+}
+
+// Apply changes the volume
+func (e VolumeEvent) Apply(p *VoicePool) {
+	if v := p.Get(e.Channel); v != nil {
+		v.Volume = e.Volume
+	}
+}
+
+// PanEvent changes the panning position of the voice playing on a channel
+type PanEvent struct {
+	Channel int
+	Pan     panning.Position
+}
+
+// Apply changes the panning position
+func (e PanEvent) Apply(p *VoicePool) {
+	if v := p.Get(e.Channel); v != nil {
+		v.Pan = e.Pan
+	}
+}
+
+type scheduledEvent struct {
+	frame int64
+	ev    Event
+}
+
+// eventQueue is a list of events ordered by frame, then by the order they were scheduled
+type eventQueue []scheduledEvent
+
+func (q *eventQueue) push(frame int64, ev Event) {
+	i := sort.Search(len(*q), func(i int) bool {
+		return (*q)[i].frame > frame
+	})
+	*q = append(*q, scheduledEvent{})
+	copy((*q)[i+1:], (*q)[i:])
+	(*q)[i] = scheduledEvent{frame: frame, ev: ev}
+}
+
+// applyUntil applies all the events scheduled at or before `frame`
+func (q *eventQueue) applyUntil(frame int64, p *VoicePool) {
+	n := 0
+	for n < len(*q) && (*q)[n].frame <= frame {
+		(*q)[n].ev.Apply(p)
+		n++
+	}
+	if n > 0 {
+		*q = append((*q)[:0], (*q)[n:]...)
+	}
+}
+
+// nextFrame returns the frame of the next scheduled event, if there is one
+func (q eventQueue) nextFrame() (int64, bool) {
+	if len(q) == 0 {
+		return 0, false
+	}
+	return q[0].frame, true
+}
@@ -0,0 +1,105 @@
+package mixing
+
+import (
+	"testing"
+
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// renderBlocks renders `blocks` blocks of `blockSize` frames and returns the first channel of each frame
+func renderBlocks(s *StreamMixer, blocks int, blockSize int) []volume.Volume {
+	var out []volume.Volume
+	for b := 0; b < blocks; b++ {
+		for _, samp := range s.Render(blockSize) {
+			out = append(out, samp.StaticMatrix[0])
+		}
+	}
+	return out
+}
+
+func TestEventsApplyAtExactFrame(t *testing.T) {
+	const blockSize = 256
+	s := newTestStreamMixer(t, 2)
+	s.RampLength = 0
+	voice := NewVoice(sampling.NewSampler(constStream(1), sampling.Pos{}, 1), 1, panning.CenterAhead)
+	s.NoteOn(0, voice, NewNoteActionCut)
+	unity := s.PanMixer.GetMixingMatrix(panning.CenterAhead).StaticMatrix[0]
+
+	// mid-block, on a block boundary, just before a boundary, and several at the same frame
+	// where the one scheduled last wins
+	s.Schedule(100, VolumeEvent{Channel: 0, Volume: 0.5})
+	s.Schedule(blockSize, VolumeEvent{Channel: 0, Volume: 0.25})
+	s.Schedule(2*blockSize-1, VolumeEvent{Channel: 0, Volume: 0.75})
+	s.Schedule(600, VolumeEvent{Channel: 0, Volume: 0.1})
+	s.Schedule(600, VolumeEvent{Channel: 0, Volume: 0.2})
+	s.Schedule(600, VolumeEvent{Channel: 0, Volume: 0.3})
+	s.Schedule(900, NoteCutEvent{Channel: 0})
+	// scheduled out of order
+	s.Schedule(700, VolumeEvent{Channel: 0, Volume: 0.6})
+
+	out := renderBlocks(s, 4, blockSize)
+	want := func(f int) volume.Volume {
+		switch {
+		case f < 100:
+			return 1
+		case f < blockSize:
+			return 0.5
+		case f < 2*blockSize-1:
+			return 0.25
+		case f < 600:
+			return 0.75
+		case f < 700:
+			return 0.3
+		case f < 900:
+			return 0.6
+		default:
+			return 0
+		}
+	}
+	for f, got := range out {
+		if w := want(f) * unity; !volumeClose(got, w) {
+			t.Fatalf("frame %d: got %v, want %v", f, got, w)
+		}
+	}
+}
+
+func TestNoteOnEventStartsAtExactFrame(t *testing.T) {
+	for _, frame := range []int64{0, 1, 63, 64, 65, 127} {
+		s := newTestStreamMixer(t, 2)
+		s.RampLength = 0
+		voice := NewVoice(sampling.NewSampler(constStream(1), sampling.Pos{}, 1), 1, panning.CenterAhead)
+		s.Schedule(frame, NoteOnEvent{Channel: 0, Voice: voice, Action: NewNoteActionCut})
+
+		out := renderBlocks(s, 2, 64)
+		for f, got := range out {
+			if sounding := int64(f) >= frame; (got != 0) != sounding {
+				t.Fatalf("note on at %d: frame %d is %v", frame, f, got)
+			}
+		}
+	}
+}
+
+func TestPastEventsApplyAtNextRender(t *testing.T) {
+	s := newTestStreamMixer(t, 2)
+	s.RampLength = 0
+	voice := NewVoice(sampling.NewSampler(constStream(1), sampling.Pos{}, 1), 1, panning.CenterAhead)
+	s.NoteOn(0, voice, NewNoteActionCut)
+	renderBlocks(s, 1, 100)
+
+	s.Schedule(50, VolumeEvent{Channel: 0, Volume: 0})
+	if s.frame != 100 {
+		t.Fatalf("frame %d, want 100", s.frame)
+	}
+	for f, got := range renderBlocks(s, 1, 10) {
+		if got != 0 {
+			t.Fatalf("frame %d after a past event: got %v, want 0", 100+f, got)
+		}
+	}
+}
+
+func volumeClose(a volume.Volume, b volume.Volume) bool {
+	d := a - b
+	return d < 1e-6 && d > -1e-6
+}
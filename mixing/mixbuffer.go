@@ -55,11 +55,27 @@ func (m *MixBuffer) MixInSample(d SampleMixIn) {
 func (m *MixBuffer) Add(pos int, rhs *MixBuffer, volMtx volume.Matrix) {
 	maxLen := len(*rhs)
 	for i := 0; i < maxLen; i++ {
+		if (*rhs)[i].Channels == 0 {
+			// silence
+			continue
+		}
 		out := volMtx.ApplyToMatrix((*rhs)[i])
 		(*m)[pos+i].Accumulate(out)
 	}
 }
 
+// SetChannels converts every entry of the mix buffer to the number of channels provided.
+// Silent entries become zeroed entries with that many channels
+func (m MixBuffer) SetChannels(channels int) {
+	for i, samp := range m {
+		if samp.Channels == 0 {
+			m[i] = volume.Matrix{Channels: channels}
+			continue
+		}
+		m[i] = samp.ToChannels(channels)
+	}
+}
+
 // ToRenderData converts a mixbuffer into a byte stream intended to be
 // output to the output sound device
 func (m *MixBuffer) ToRenderData(samples int, channels int, mixerVolume volume.Volume, formatter sampling.Formatter) []byte {
@@ -8,6 +8,14 @@ import (
 // Mixer is a manager for mixing multiple single- and multi-channel samples into a single multi-channel output stream
 type Mixer struct {
 	Channels int
+	// Buses is an optional routing graph for the mixed channels. If nil, all channels are mixed directly
+	Buses *BusGraph
+	// Effects is applied to the final mix before it is converted to the output format
+	Effects EffectChain
+	// AutoGain adapts the mixer volume to the level of the final mix. If nil, the mixer volume is used as-is
+	AutoGain *AutoGain
+	// Clipper is the output stage applied to the final mix. If nil, the mix is hard-clipped
+	Clipper *Clipper
 }
 
 // NewMixBuffer returns a mixer buffer with a number of channels
@@ -22,56 +30,85 @@ func GetDefaultMixerVolume(numMixedChannels int) volume.Volume {
 	return 1.0 / volume.Volume(numMixedChannels)
 }
 
-// Flatten will to a final saturation mix of all the row's channel data into a single output buffer
-func (m Mixer) Flatten(panmixer PanMixer, samplesLen int, row []ChannelData, mixerVolume volume.Volume, sampleFormat sampling.Format) []byte {
+// mixRow mixes all the row's channel data into a single mix buffer, routing it through the buses if there are any
+func (m Mixer) mixRow(panmixer PanMixer, samplesLen int, row []ChannelData) MixBuffer {
+	if m.Buses != nil {
+		m.Buses.beginMix(samplesLen)
+	}
 	data := m.NewMixBuffer(samplesLen)
-	formatter := sampling.GetFormatter(sampleFormat)
-	for _, rdata := range row {
+	for ch, rdata := range row {
+		out := data
+		if m.Buses != nil {
+			out = m.Buses.ChannelBus(ch).buf
+		}
 		for _, cdata := range rdata {
 			if cdata.Flush != nil {
 				cdata.Flush()
 			}
 			if len(cdata.Data) > 0 {
 				volMtx := panmixer.GetMixingMatrix(cdata.Pan).Apply(cdata.Volume)
-				data.Add(cdata.Pos, &cdata.Data, volMtx)
+				if len(cdata.Effects) == 0 {
+					out.Add(cdata.Pos, &cdata.Data, volMtx)
+					continue
+				}
+				wet := m.NewMixBuffer(len(cdata.Data))
+				wet.Add(0, &cdata.Data, volMtx)
+				wet.SetChannels(panmixer.NumChannels())
+				cdata.Effects.Process(wet)
+				out.Add(cdata.Pos, &wet, uniformMatrix(panmixer.NumChannels(), 1))
 			}
 		}
 	}
+	if m.Buses != nil {
+		data = m.Buses.finishMix(panmixer)
+	}
+	m.applyEffects(panmixer, data)
+	return data
+}
+
+// applyEffects applies the master effects to the final mix
+func (m Mixer) applyEffects(panmixer PanMixer, data MixBuffer) {
+	if len(m.Effects) == 0 {
+		return
+	}
+	data.SetChannels(panmixer.NumChannels())
+	m.Effects.Process(data)
+}
+
+// applyOutputStage runs the final mix through the automatic gain and the clipper, if there are any,
+// and returns the mixer volume that's left to apply when rendering
+func (m Mixer) applyOutputStage(panmixer PanMixer, data MixBuffer, mixerVolume volume.Volume) volume.Volume {
+	if m.AutoGain != nil {
+		m.AutoGain.Process(data, panmixer.NumChannels(), mixerVolume)
+		mixerVolume = 1
+	}
+	if m.Clipper != nil {
+		m.Clipper.Process(data, panmixer.NumChannels(), mixerVolume)
+		mixerVolume = 1
+	}
+	return mixerVolume
+}
+
+// Flatten will to a final saturation mix of all the row's channel data into a single output buffer
+func (m Mixer) Flatten(panmixer PanMixer, samplesLen int, row []ChannelData, mixerVolume volume.Volume, sampleFormat sampling.Format) []byte {
+	data := m.mixRow(panmixer, samplesLen, row)
+	mixerVolume = m.applyOutputStage(panmixer, data, mixerVolume)
+	formatter := sampling.GetFormatter(sampleFormat)
 	return data.ToRenderData(samplesLen, m.Channels, mixerVolume, formatter)
 }
 
 // FlattenToInts runs a flatten on the channel data into separate channel data of int32 variety
 // these int32s still respect the bitsPerSample size
 func (m Mixer) FlattenToInts(panmixer PanMixer, samplesLen, bitsPerSample int, row []ChannelData, mixerVolume volume.Volume) [][]int32 {
-	data := m.NewMixBuffer(samplesLen)
-	for _, rdata := range row {
-		for _, cdata := range rdata {
-			if cdata.Flush != nil {
-				cdata.Flush()
-			}
-			if len(cdata.Data) > 0 {
-				volMtx := panmixer.GetMixingMatrix(cdata.Pan).Apply(cdata.Volume)
-				data.Add(cdata.Pos, &cdata.Data, volMtx)
-			}
-		}
-	}
+	data := m.mixRow(panmixer, samplesLen, row)
+	mixerVolume = m.applyOutputStage(panmixer, data, mixerVolume)
 	return data.ToIntStream(panmixer.NumChannels(), samplesLen, bitsPerSample, mixerVolume)
 }
 
 // FlattenTo will to a final saturation mix of all the row's channel data into a single output buffer
 func (m Mixer) FlattenTo(resultBuffers [][]byte, panmixer PanMixer, samplesLen int, row []ChannelData, mixerVolume volume.Volume, sampleFormat sampling.Format) {
-	data := m.NewMixBuffer(samplesLen)
+	data := m.mixRow(panmixer, samplesLen, row)
+	mixerVolume = m.applyOutputStage(panmixer, data, mixerVolume)
 	formatter := sampling.GetFormatter(sampleFormat)
-	for _, rdata := range row {
-		for _, cdata := range rdata {
-			if cdata.Flush != nil {
-				cdata.Flush()
-			}
-			if len(cdata.Data) > 0 {
-				volMtx := panmixer.GetMixingMatrix(cdata.Pan).Apply(cdata.Volume)
-				data.Add(cdata.Pos, &cdata.Data, volMtx)
-			}
-		}
-	}
 	data.ToRenderDataWithBufs(resultBuffers, samplesLen, mixerVolume, formatter)
 }
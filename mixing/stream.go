@@ -0,0 +1,196 @@
+package mixing
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// DefaultStreamBlockSize is the default number of samples rendered at a time by a StreamMixer
+const DefaultStreamBlockSize = 512
+
+var (
+	// ErrUnsupportedChannels is returned when a stream mixer is created with a channel count that has no pan mixer
+	ErrUnsupportedChannels = errors.New("unsupported number of output channels")
+	// ErrUnsupportedFormat is returned when a stream mixer is created with a sample format that has no formatter
+	ErrUnsupportedFormat = errors.New("unsupported output sample format")
+)
+
+// StreamMixer is a mixer that owns a set of voices and renders them on demand
+// into a continuous output stream. It implements io.Reader, so it can be handed
+// directly to pull-style audio backends.
+type StreamMixer struct {
+	Mixer
+	PanMixer    PanMixer
+	MixerVolume volume.Volume
+	BlockSize   int
+	// RampLength is the number of samples over which a voice's volume and panning
+	// changes are interpolated to avoid clicks. If zero, changes are applied instantly
+	RampLength int
+
+	mu        sync.Mutex
+	formatter sampling.Formatter
+	voices    *VoicePool
+	events    eventQueue
+	frame     int64
+	pending   []byte
+	pendPos   int
+}
+
+// NewStreamMixer returns a stream mixer that renders `channels` output channels
+// in the sample format provided, or an error if either is unsupported
+func NewStreamMixer(channels int, sampleFormat sampling.Format) (*StreamMixer, error) {
+	panmixer := GetPanMixer(channels)
+	if panmixer == nil {
+		return nil, ErrUnsupportedChannels
+	}
+	formatter := sampling.GetFormatter(sampleFormat)
+	if formatter == nil {
+		return nil, ErrUnsupportedFormat
+	}
+	return &StreamMixer{
+		Mixer: Mixer{
+			Channels: channels,
+		},
+		PanMixer:    panmixer,
+		MixerVolume: 1,
+		BlockSize:   DefaultStreamBlockSize,
+		RampLength:  DefaultRampLength,
+		formatter:   formatter,
+		voices:      NewVoicePool(0, StealOldest),
+	}, nil
+}
+
+// FrameSize returns the size in bytes of a single multichannel sample frame of the output stream
+func (s *StreamMixer) FrameSize() int {
+	return s.formatter.Size() * s.Channels
+}
+
+// SetPolyphony sets the maximum number of voices allowed to play at once
+// and the method of stealing voices when that limit is reached.
+// A `maxVoices` value of zero removes the limit
+func (s *StreamMixer) SetPolyphony(maxVoices int, stealMode StealMode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voices.MaxVoices = maxVoices
+	s.voices.StealMode = stealMode
+}
+
+// AddVoice adds a voice to the set of voices being rendered
+func (s *StreamMixer) AddVoice(v *Voice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voices.Add(v)
+}
+
+// RemoveVoice removes a voice from the set of voices being rendered
+func (s *StreamMixer) RemoveVoice(v *Voice) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voices.Remove(v)
+}
+
+// NoteOn starts a voice on a channel, applying the new note action to the
+// voice previously playing on it
+func (s *StreamMixer) NoteOn(channel int, v *Voice, nna NewNoteAction) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voices.NoteOn(channel, v, nna)
+}
+
+// NoteOff releases the voice playing on a channel
+func (s *StreamMixer) NoteOff(channel int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.voices.NoteOff(channel)
+}
+
+// NumVoices returns the number of voices currently playing
+func (s *StreamMixer) NumVoices() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.voices.Voices())
+}
+
+// Position returns the sample frame that will be rendered next
+func (s *StreamMixer) Position() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.frame
+}
+
+// Schedule queues an event to be applied at an exact sample frame of the output stream.
+// Events scheduled for frames that have already been rendered are applied at the start
+// of the next render.
+func (s *StreamMixer) Schedule(frame int64, ev Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events.push(frame, ev)
+}
+
+// Render mixes the next `samples` samples of all the voices into a new mix buffer
+func (s *StreamMixer) Render(samples int) MixBuffer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.render(samples)
+}
+
+func (s *StreamMixer) render(samples int) MixBuffer {
+	if s.Buses != nil {
+		s.Buses.beginMix(samples)
+	}
+	data := s.NewMixBuffer(samples)
+	pos := 0
+	for pos < samples {
+		s.events.applyUntil(s.frame+int64(pos), s.voices)
+
+		// split the block at the next event
+		next := samples
+		if frame, ok := s.events.nextFrame(); ok && frame < s.frame+int64(samples) {
+			next = int(frame - s.frame)
+		}
+
+		for _, v := range s.voices.Voices() {
+			out := data
+			if s.Buses != nil {
+				out = s.Buses.busBuffer(v.Bus)
+			}
+			v.render(out, s.PanMixer, pos, next-pos, s.RampLength)
+		}
+		s.voices.Reap()
+		pos = next
+	}
+	s.frame += int64(samples)
+	if s.Buses != nil {
+		data = s.Buses.finishMix(s.PanMixer)
+	}
+	s.applyEffects(s.PanMixer, data)
+	return data
+}
+
+// Read renders the voices into the byte slice provided, in the output sample format.
+// The stream is endless, so Read always fills `p` completely.
+func (s *StreamMixer) Read(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for n < len(p) {
+		if s.pendPos >= len(s.pending) {
+			blockSize := s.BlockSize
+			if blockSize <= 0 {
+				blockSize = DefaultStreamBlockSize
+			}
+			data := s.render(blockSize)
+			mixerVolume := s.applyOutputStage(s.PanMixer, data, s.MixerVolume)
+			s.pending = data.ToRenderData(blockSize, s.Channels, mixerVolume, s.formatter)
+			s.pendPos = 0
+		}
+		c := copy(p[n:], s.pending[s.pendPos:])
+		s.pendPos += c
+		n += c
+	}
+	return n, nil
+}
@@ -0,0 +1,177 @@
+package mixing
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// constStream is a mono sample stream with the same value at every position
+type constStream volume.Volume
+
+func (c constStream) GetSample(pos sampling.Pos) volume.Matrix {
+	return volume.Matrix{
+		StaticMatrix: volume.StaticMatrix{volume.Volume(c)},
+		Channels:     1,
+	}
+}
+
+func newTestStreamMixer(t *testing.T, channels int) *StreamMixer {
+	t.Helper()
+	s, err := NewStreamMixer(channels, sampling.Format32BitLEFloat)
+	if err != nil {
+		t.Fatalf("NewStreamMixer: %v", err)
+	}
+	return s
+}
+
+func TestNewStreamMixerInvalid(t *testing.T) {
+	if _, err := NewStreamMixer(3, sampling.Format16BitLESigned); !errors.Is(err, ErrUnsupportedChannels) {
+		t.Errorf("3 channels: got %v, want %v", err, ErrUnsupportedChannels)
+	}
+	if _, err := NewStreamMixer(2, sampling.Format(255)); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("unknown format: got %v, want %v", err, ErrUnsupportedFormat)
+	}
+}
+
+func TestStreamMixerReadToMemory(t *testing.T) {
+	const frames = 2000
+	s := newTestStreamMixer(t, 2)
+	s.RampLength = 0
+	s.AddVoice(NewVoice(sampling.NewSampler(constStream(0.5), sampling.Pos{}, 1), 1, panning.CenterAhead))
+
+	// read in odd-sized chunks so that reads straddle render blocks
+	var sink bytes.Buffer
+	chunk := make([]byte, 333)
+	for sink.Len() < frames*s.FrameSize() {
+		n, err := s.Read(chunk)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		if n != len(chunk) {
+			t.Fatalf("Read filled %d bytes, want %d", n, len(chunk))
+		}
+		sink.Write(chunk[:n])
+	}
+	data := sink.Bytes()[:frames*s.FrameSize()]
+
+	want := s.PanMixer.GetMixingMatrix(panning.CenterAhead).Apply(0.5)
+	formatter := sampling.GetFormatter(sampling.Format32BitLEFloat)
+	for i := 0; i < frames; i++ {
+		for c := 0; c < 2; c++ {
+			v, err := formatter.ReadAt(data, int64((i*2+c)*formatter.Size()))
+			if err != nil {
+				t.Fatalf("ReadAt: %v", err)
+			}
+			if math.Abs(float64(v-want.StaticMatrix[c])) > 1e-6 {
+				t.Fatalf("frame %d channel %d: got %v, want %v", i, c, v, want.StaticMatrix[c])
+			}
+		}
+	}
+}
+
+func TestStreamMixerIsReader(t *testing.T) {
+	s := newTestStreamMixer(t, 1)
+	var sink bytes.Buffer
+	n, err := io.CopyN(&sink, s, 4096)
+	if err != nil || n != 4096 {
+		t.Fatalf("CopyN: got %d, %v", n, err)
+	}
+	if got := s.Position(); got < 4096/int64(s.FrameSize()) {
+		t.Errorf("Position: got %d, want at least %d", got, 4096/s.FrameSize())
+	}
+}
+
+// shortStream is a mono sample stream of a fixed length
+type shortStream int
+
+func (s shortStream) GetSample(pos sampling.Pos) volume.Matrix {
+	if pos.Pos < 0 || pos.Pos >= int(s) {
+		return volume.Matrix{Channels: 1}
+	}
+	return volume.Matrix{
+		StaticMatrix: volume.StaticMatrix{0.5},
+		Channels:     1,
+	}
+}
+
+func (s shortStream) Len() int {
+	return int(s)
+}
+
+func TestStreamMixerFreesEndedVoices(t *testing.T) {
+	s := newTestStreamMixer(t, 2)
+	s.AddVoice(NewVoice(sampling.NewSampler(shortStream(100), sampling.Pos{}, 1), 1, panning.CenterAhead))
+	s.Render(1000)
+	if n := s.NumVoices(); n != 0 {
+		t.Errorf("NumVoices after the end of the sample: got %d, want 0", n)
+	}
+}
+
+// maxStep returns the largest change between neighboring samples of any channel,
+// counting the step up from silence before the first sample
+func maxStep(buf MixBuffer, channels int) float64 {
+	var prev [volume.MaxChannels]float64
+	var step float64
+	for _, samp := range buf {
+		for c := 0; c < channels; c++ {
+			var v float64
+			if c < samp.Channels {
+				v = float64(samp.StaticMatrix[c])
+			}
+			step = math.Max(step, math.Abs(v-prev[c]))
+			prev[c] = v
+		}
+	}
+	return step
+}
+
+func TestStreamMixerRampsAvoidDiscontinuities(t *testing.T) {
+	const level = 0.5
+	// the largest step allowed is a full-scale change spread over the ramp
+	limit := level/DefaultRampLength + 1e-6
+
+	tests := []struct {
+		name   string
+		events func(s *StreamMixer)
+	}{
+		{"ramp-in", func(s *StreamMixer) {}},
+		{"volume change", func(s *StreamMixer) {
+			s.Schedule(300, VolumeEvent{Channel: 0, Volume: 0.1})
+			s.Schedule(700, VolumeEvent{Channel: 0, Volume: 1})
+		}},
+		{"pan change", func(s *StreamMixer) {
+			s.Schedule(300, PanEvent{Channel: 0, Pan: panning.MakeStereoPosition(0, 0, 1)})
+			s.Schedule(700, PanEvent{Channel: 0, Pan: panning.MakeStereoPosition(1, 0, 1)})
+		}},
+		{"cut", func(s *StreamMixer) {
+			s.Schedule(500, NoteCutEvent{Channel: 0})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			render := func(rampLength int) MixBuffer {
+				s := newTestStreamMixer(t, 2)
+				s.RampLength = rampLength
+				s.NoteOn(0, NewVoice(sampling.NewSampler(constStream(level), sampling.Pos{}, 1), 1, panning.CenterAhead), NewNoteActionCut)
+				tt.events(s)
+				return s.Render(1200)
+			}
+
+			if step := maxStep(render(DefaultRampLength), 2); step > limit {
+				t.Errorf("max step with ramping: got %v, want at most %v", step, limit)
+			}
+			// without ramping, the same changes must click, or the test proves nothing
+			if step := maxStep(render(0), 2); step <= limit {
+				t.Errorf("max step without ramping: got %v, want more than %v", step, limit)
+			}
+		})
+	}
+}
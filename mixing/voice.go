@@ -0,0 +1,184 @@
+package mixing
+
+import (
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// DefaultRampLength is the default number of samples over which a voice's mixing matrix
+// is interpolated when its volume or panning changes
+const DefaultRampLength = 64
+
+// DefaultVoiceFadeRate is the default amount of fade volume removed per sample from a fading voice
+const DefaultVoiceFadeRate = volume.Volume(1.0 / 1024.0)
+
+// Voice is a single playing sound owned by a StreamMixer
+type Voice struct {
+	Sample   sampling.Sampler
+	Volume   volume.Volume
+	Pan      panning.Position
+	Priority int
+	// Bus is the name of the bus the voice is mixed into when the mixer has a routing graph.
+	// If empty or unknown, the master bus is used
+	Bus string
+	// FadeRate is the amount of fade volume removed per sample while fading out.
+	// If zero, DefaultVoiceFadeRate is used
+	FadeRate volume.Volume
+
+	age       uint64
+	fade      volume.Volume
+	stealRate volume.Volume
+	fading    bool
+	keyOff    bool
+	stolen    bool
+	cutting   bool
+	done      bool
+	level     volume.Volume
+	mtx       volume.Matrix
+	target    volume.Matrix
+	rampLeft  int
+}
+
+// NewVoice returns a voice that plays the sampler provided
+func NewVoice(sample sampling.Sampler, vol volume.Volume, pan panning.Position) *Voice {
+	return &Voice{
+		Sample: sample,
+		Volume: vol,
+		Pan:    pan,
+	}
+}
+
+// KeyOff releases the voice, notifying the sampler if it implements sampling.Releaser
+func (v *Voice) KeyOff() {
+	if v.keyOff {
+		return
+	}
+	v.keyOff = true
+	if r, ok := v.Sample.(sampling.Releaser); ok {
+		r.Release()
+	}
+}
+
+// Fade starts fading the voice out at its fade rate
+func (v *Voice) Fade() {
+	v.fading = true
+}
+
+// Cut stops the voice, ramping it out over the mixer's ramp length
+func (v *Voice) Cut() {
+	v.cutting = true
+}
+
+// IsKeyOff returns true if the voice has been released
+func (v *Voice) IsKeyOff() bool {
+	return v.keyOff
+}
+
+// IsFading returns true if the voice is fading out
+func (v *Voice) IsFading() bool {
+	return v.fading
+}
+
+// IsCut returns true if the voice has been cut and is ramping out
+func (v *Voice) IsCut() bool {
+	return v.cutting
+}
+
+// IsDone returns true if the voice has finished playing and can be freed
+func (v *Voice) IsDone() bool {
+	return v.done
+}
+
+// Level returns the peak output level of the voice from the last time it was rendered
+func (v *Voice) Level() volume.Volume {
+	return v.level
+}
+
+func (v *Voice) reset(age uint64) {
+	v.age = age
+	v.fade = 1
+	v.stealRate = 0
+	v.fading = false
+	v.keyOff = false
+	v.stolen = false
+	v.cutting = false
+	v.done = false
+	v.level = 0
+	v.mtx = volume.Matrix{}
+	v.target = volume.Matrix{}
+	v.rampLeft = 0
+}
+
+func (v *Voice) fadeRate() volume.Volume {
+	if v.stealRate > 0 {
+		return v.stealRate
+	}
+	if v.FadeRate > 0 {
+		return v.FadeRate
+	}
+	return DefaultVoiceFadeRate
+}
+
+// render mixes `samples` samples of the voice into the mix buffer starting at `pos`,
+// interpolating from the previous mixing matrix to the current one over `rampLen` samples
+func (v *Voice) render(out MixBuffer, panmixer PanMixer, pos int, samples int, rampLen int) {
+	v.level = 0
+	if v.done {
+		return
+	}
+	if v.Sample == nil {
+		v.done = true
+		return
+	}
+
+	target := panmixer.GetMixingMatrix(v.Pan).Apply(v.Volume)
+	if v.cutting {
+		target = volume.Matrix{Channels: target.Channels}
+	}
+	if v.mtx.Channels == 0 {
+		// new voices ramp in from silence
+		v.mtx = volume.Matrix{Channels: target.Channels}
+	}
+	if target != v.target {
+		v.target = target
+		v.rampLeft = rampLen
+	}
+
+	ender, _ := v.Sample.(sampling.Ender)
+	for i := 0; i < samples; i++ {
+		if (v.cutting && v.rampLeft <= 0) || (ender != nil && ender.IsEnded()) {
+			v.done = true
+			return
+		}
+		if v.rampLeft > 0 {
+			v.mtx = v.mtx.Lerp(v.target, 1/float32(v.rampLeft))
+			v.rampLeft--
+		} else {
+			v.mtx = v.target
+		}
+
+		dry := v.Sample.GetSample()
+		mixed := v.mtx.ApplyToMatrix(dry.Apply(v.fade))
+		out[pos+i].Accumulate(mixed)
+		for c := 0; c < mixed.Channels; c++ {
+			l := mixed.StaticMatrix[c]
+			if l < 0 {
+				l = -l
+			}
+			if l > v.level {
+				v.level = l
+			}
+		}
+		v.Sample.Advance()
+
+		if v.fading {
+			v.fade -= v.fadeRate()
+			if v.fade <= 0 {
+				v.fade = 0
+				v.done = true
+				return
+			}
+		}
+	}
+}
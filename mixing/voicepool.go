@@ -0,0 +1,199 @@
+package mixing
+
+import "github.com/gotracker/gomixing/volume"
+
+// StealMode is the method used to select a voice to steal when the polyphony limit is reached
+type StealMode uint8
+
+const (
+	// StealOldest steals the voice that was started the longest time ago
+	StealOldest = StealMode(iota)
+	// StealQuietest steals the voice with the lowest output level
+	StealQuietest
+	// StealLowestPriority steals the voice with the lowest priority, oldest first
+	StealLowestPriority
+)
+
+// NewNoteAction is the action taken on a channel's currently playing voice when a new note starts on it
+type NewNoteAction uint8
+
+const (
+	// NewNoteActionCut stops the previous voice immediately
+	NewNoteActionCut = NewNoteAction(iota)
+	// NewNoteActionContinue lets the previous voice continue playing in the background
+	NewNoteActionContinue
+	// NewNoteActionNoteOff releases the previous voice and lets it continue in the background
+	NewNoteActionNoteOff
+	// NewNoteActionFade fades out the previous voice in the background
+	NewNoteActionFade
+)
+
+// VoicePool is a set of playing voices with a maximum polyphony
+type VoicePool struct {
+	// MaxVoices is the maximum number of voices allowed to play at once. If zero, the pool is unlimited
+	MaxVoices int
+	StealMode StealMode
+	// StealFadeRate is the amount of fade volume removed per sample from a stolen voice.
+	// If zero, the voice's own fade rate is used. The voice's FadeRate is left unchanged
+	StealFadeRate volume.Volume
+
+	voices   []*Voice
+	channels map[int]*Voice
+	seq      uint64
+}
+
+// NewVoicePool returns a voice pool with the polyphony limit and stealing mode provided
+func NewVoicePool(maxVoices int, stealMode StealMode) *VoicePool {
+	return &VoicePool{
+		MaxVoices: maxVoices,
+		StealMode: stealMode,
+		channels:  make(map[int]*Voice),
+	}
+}
+
+// Voices returns the voices currently in the pool
+func (p *VoicePool) Voices() []*Voice {
+	return p.voices
+}
+
+// Get returns the foreground voice playing on a channel, or nil if none is
+func (p *VoicePool) Get(channel int) *Voice {
+	return p.channels[channel]
+}
+
+// Add adds a voice to the pool that isn't bound to a channel, stealing another voice if needed.
+// A voice that is already in the pool is left as it is
+func (p *VoicePool) Add(v *Voice) {
+	if p.contains(v) {
+		return
+	}
+	p.steal()
+	p.seq++
+	v.reset(p.seq)
+	p.voices = append(p.voices, v)
+}
+
+func (p *VoicePool) contains(v *Voice) bool {
+	for _, o := range p.voices {
+		if o == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Remove removes a voice from the pool immediately
+func (p *VoicePool) Remove(v *Voice) {
+	for i, o := range p.voices {
+		if o == v {
+			p.voices = append(p.voices[:i], p.voices[i+1:]...)
+			break
+		}
+	}
+	for ch, o := range p.channels {
+		if o == v {
+			delete(p.channels, ch)
+		}
+	}
+}
+
+// NoteOn starts a voice on a channel, applying the new note action to the
+// voice previously playing on it
+func (p *VoicePool) NoteOn(channel int, v *Voice, nna NewNoteAction) {
+	if prev := p.channels[channel]; prev != nil && prev != v {
+		switch nna {
+		case NewNoteActionCut:
+			prev.Cut()
+		case NewNoteActionNoteOff:
+			prev.KeyOff()
+		case NewNoteActionFade:
+			prev.Fade()
+		}
+		delete(p.channels, channel)
+	}
+	p.Reap()
+	p.Add(v)
+	if p.channels == nil {
+		p.channels = make(map[int]*Voice)
+	}
+	p.channels[channel] = v
+}
+
+// NoteOff releases the foreground voice playing on a channel
+func (p *VoicePool) NoteOff(channel int) {
+	if v := p.channels[channel]; v != nil {
+		v.KeyOff()
+	}
+}
+
+// Reap frees all the voices that have finished playing
+func (p *VoicePool) Reap() {
+	n := 0
+	for _, v := range p.voices {
+		if !v.done {
+			p.voices[n] = v
+			n++
+		}
+	}
+	for i := n; i < len(p.voices); i++ {
+		p.voices[i] = nil
+	}
+	p.voices = p.voices[:n]
+	for ch, v := range p.channels {
+		if v.done {
+			delete(p.channels, ch)
+		}
+	}
+}
+
+// steal fades out voices until there is room for a new one
+func (p *VoicePool) steal() {
+	if p.MaxVoices <= 0 {
+		return
+	}
+	for {
+		active := 0
+		for _, v := range p.voices {
+			if !v.stolen && !v.cutting && !v.done {
+				active++
+			}
+		}
+		if active < p.MaxVoices {
+			return
+		}
+		victim := p.selectVictim()
+		if victim == nil {
+			return
+		}
+		victim.stolen = true
+		victim.stealRate = p.StealFadeRate
+		victim.Fade()
+	}
+}
+
+func (p *VoicePool) selectVictim() *Voice {
+	var victim *Voice
+	for _, v := range p.voices {
+		if v.stolen || v.cutting || v.done {
+			continue
+		}
+		if victim == nil || p.isBetterVictim(v, victim) {
+			victim = v
+		}
+	}
+	return victim
+}
+
+func (p *VoicePool) isBetterVictim(v, than *Voice) bool {
+	switch p.StealMode {
+	case StealQuietest:
+		if v.level != than.level {
+			return v.level < than.level
+		}
+	case StealLowestPriority:
+		if v.Priority != than.Priority {
+			return v.Priority < than.Priority
+		}
+	}
+	return v.age < than.age
+}
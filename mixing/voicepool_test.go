@@ -0,0 +1,195 @@
+package mixing
+
+import (
+	"testing"
+
+	"github.com/gotracker/gomixing/panning"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// releaseSampler records whether it has been released
+type releaseSampler struct {
+	sampling.Sampler
+	released bool
+}
+
+func (r *releaseSampler) Release() {
+	r.released = true
+}
+
+func newTestVoice() *Voice {
+	return NewVoice(&releaseSampler{Sampler: sampling.NewSampler(constStream(0.5), sampling.Pos{}, 1)}, 1, panning.CenterAhead)
+}
+
+// activeVoices returns the voices that haven't been stolen, cut or finished
+func activeVoices(p *VoicePool) []*Voice {
+	var active []*Voice
+	for _, v := range p.Voices() {
+		if !v.stolen && !v.cutting && !v.done {
+			active = append(active, v)
+		}
+	}
+	return active
+}
+
+func TestVoicePoolPolyphonyLimit(t *testing.T) {
+	p := NewVoicePool(3, StealOldest)
+	voices := make([]*Voice, 5)
+	for i := range voices {
+		voices[i] = newTestVoice()
+		p.Add(voices[i])
+		if n := len(activeVoices(p)); n > 3 {
+			t.Fatalf("after adding %d voices: %d active, want at most 3", i+1, n)
+		}
+	}
+	for i, v := range voices {
+		if stolen := i < 2; v.stolen != stolen || v.IsFading() != stolen {
+			t.Errorf("voice %d: stolen %v, fading %v, want %v", i, v.stolen, v.IsFading(), stolen)
+		}
+	}
+
+	unlimited := NewVoicePool(0, StealOldest)
+	for i := 0; i < 100; i++ {
+		unlimited.Add(newTestVoice())
+	}
+	if n := len(activeVoices(unlimited)); n != 100 {
+		t.Errorf("unlimited pool: %d active voices, want 100", n)
+	}
+}
+
+func TestVoicePoolStealModes(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       StealMode
+		levels     []volume.Volume
+		priorities []int
+		victim     int
+	}{
+		{name: "oldest", mode: StealOldest, levels: []volume.Volume{0.1, 0.5, 0.2}, victim: 0},
+		{name: "quietest", mode: StealQuietest, levels: []volume.Volume{0.5, 0.1, 0.3}, victim: 1},
+		{name: "quietest tie goes to oldest", mode: StealQuietest, levels: []volume.Volume{0.5, 0.2, 0.2}, victim: 1},
+		{name: "lowest priority", mode: StealLowestPriority, priorities: []int{1, 0, 2}, victim: 1},
+		{name: "lowest priority tie goes to oldest", mode: StealLowestPriority, priorities: []int{2, 1, 1}, victim: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewVoicePool(3, tt.mode)
+			voices := make([]*Voice, 3)
+			for i := range voices {
+				voices[i] = newTestVoice()
+				if tt.priorities != nil {
+					voices[i].Priority = tt.priorities[i]
+				}
+				p.Add(voices[i])
+				if tt.levels != nil {
+					voices[i].level = tt.levels[i]
+				}
+			}
+			p.Add(newTestVoice())
+			for i, v := range voices {
+				if stolen := i == tt.victim; v.stolen != stolen {
+					t.Errorf("voice %d: stolen %v, want %v", i, v.stolen, stolen)
+				}
+			}
+		})
+	}
+}
+
+func TestVoicePoolStealFadeRate(t *testing.T) {
+	p := NewVoicePool(1, StealOldest)
+	p.StealFadeRate = 0.25
+	v := newTestVoice()
+	v.FadeRate = 0.01
+	p.Add(v)
+	p.Add(newTestVoice())
+
+	if v.FadeRate != 0.01 {
+		t.Errorf("stealing changed the voice's FadeRate to %v", v.FadeRate)
+	}
+	if got := v.fadeRate(); got != p.StealFadeRate {
+		t.Errorf("stolen voice fades at %v, want %v", got, p.StealFadeRate)
+	}
+
+	// a stolen voice that is reused fades at its own rate again
+	p.Remove(v)
+	p.Add(v)
+	if got := v.fadeRate(); got != 0.01 {
+		t.Errorf("reused voice fades at %v, want 0.01", got)
+	}
+}
+
+func TestVoicePoolAddDuplicate(t *testing.T) {
+	p := NewVoicePool(0, StealOldest)
+	v := newTestVoice()
+	p.Add(v)
+	v.Fade()
+	p.Add(v)
+	if n := len(p.Voices()); n != 1 {
+		t.Fatalf("pool has %d voices after adding one twice, want 1", n)
+	}
+	if !v.IsFading() {
+		t.Error("adding a voice twice reset it")
+	}
+
+	p.NoteOn(0, v, NewNoteActionCut)
+	if n := len(p.Voices()); n != 1 || p.Get(0) != v {
+		t.Errorf("NoteOn with a pooled voice: %d voices, channel voice %p, want 1 and %p", n, p.Get(0), v)
+	}
+	p.NoteOn(0, v, NewNoteActionCut)
+	if v.IsCut() {
+		t.Error("retriggering a channel with its own voice cut it")
+	}
+}
+
+func TestVoicePoolNewNoteActions(t *testing.T) {
+	tests := []struct {
+		name  string
+		nna   NewNoteAction
+		check func(v *Voice) bool
+	}{
+		{name: "cut", nna: NewNoteActionCut, check: func(v *Voice) bool { return v.IsCut() && !v.IsKeyOff() && !v.IsFading() }},
+		{name: "continue", nna: NewNoteActionContinue, check: func(v *Voice) bool { return !v.IsCut() && !v.IsKeyOff() && !v.IsFading() }},
+		{name: "note off", nna: NewNoteActionNoteOff, check: func(v *Voice) bool {
+			return v.IsKeyOff() && v.Sample.(*releaseSampler).released && !v.IsCut() && !v.IsFading()
+		}},
+		{name: "fade", nna: NewNoteActionFade, check: func(v *Voice) bool { return v.IsFading() && !v.IsCut() && !v.IsKeyOff() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewVoicePool(0, StealOldest)
+			first, second := newTestVoice(), newTestVoice()
+			p.NoteOn(0, first, tt.nna)
+			p.NoteOn(0, second, tt.nna)
+			if !tt.check(first) {
+				t.Errorf("previous voice: cut %v, key off %v, fading %v", first.IsCut(), first.IsKeyOff(), first.IsFading())
+			}
+			if p.Get(0) != second {
+				t.Error("channel isn't playing the new voice")
+			}
+			if n := len(p.Voices()); n != 2 {
+				t.Errorf("%d voices in the pool, want the previous voice to keep playing in the background", n)
+			}
+		})
+	}
+}
+
+func TestVoicePoolNoteOffAndReap(t *testing.T) {
+	p := NewVoicePool(0, StealOldest)
+	a, b := newTestVoice(), newTestVoice()
+	p.NoteOn(0, a, NewNoteActionCut)
+	p.NoteOn(1, b, NewNoteActionCut)
+	p.NoteOff(1)
+	if !b.IsKeyOff() || a.IsKeyOff() {
+		t.Errorf("NoteOff(1): key off %v and %v, want only the second voice", a.IsKeyOff(), b.IsKeyOff())
+	}
+
+	a.done = true
+	p.Reap()
+	if n := len(p.Voices()); n != 1 || p.Voices()[0] != b {
+		t.Errorf("after reaping: %d voices, want only the unfinished one", n)
+	}
+	if p.Get(0) != nil {
+		t.Error("reaped voice is still bound to its channel")
+	}
+}
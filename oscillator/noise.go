@@ -0,0 +1,105 @@
+package oscillator
+
+import (
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// NoiseColor is the spectral shape of a noise generator
+type NoiseColor int
+
+const (
+	// NoiseWhite has equal power at all frequencies
+	NoiseWhite = NoiseColor(iota)
+	// NoisePink falls by 3 dB per octave
+	NoisePink
+	// NoiseBrown falls by 6 dB per octave
+	NoiseBrown
+)
+
+// Noise is a noise generator. The output depends only on the seed, so a stream replays
+// identically for the same seed. White noise may be read at any position; pink and brown noise
+// are filtered, so they are generated in order and regenerated from the start when read backwards.
+type Noise struct {
+	// Amplitude is the approximate peak level of the output
+	Amplitude float64
+
+	color NoiseColor
+	seed  uint64
+	next  int
+	last  float64
+	state [7]float64
+}
+
+// NewNoise returns a noise generator of the color provided using the seed provided
+func NewNoise(color NoiseColor, seed int64) *Noise {
+	return &Noise{
+		Amplitude: 1,
+		color:     color,
+		seed:      uint64(seed),
+	}
+}
+
+// Color returns the color of the noise
+func (n *Noise) Color() NoiseColor {
+	return n.color
+}
+
+// GetSample returns the sample at the position provided
+func (n *Noise) GetSample(pos sampling.Pos) volume.Matrix {
+	i := pos.Pos
+	if i < 0 {
+		return mono(0)
+	}
+	if n.color == NoiseWhite {
+		return mono(n.Amplitude * n.white(i))
+	}
+
+	if i < n.next-1 {
+		n.reset()
+	}
+	for n.next <= i {
+		n.last = n.filter(n.white(n.next))
+		n.next++
+	}
+	return mono(n.Amplitude * n.last)
+}
+
+// white returns the uniformly distributed white noise value (-1 to 1) at a position
+func (n *Noise) white(i int) float64 {
+	// splitmix64 of the seeded position
+	z := n.seed + uint64(i+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return float64(z>>11)/(1<<52) - 1
+}
+
+// filter shapes the next white noise value into the noise color
+func (n *Noise) filter(w float64) float64 {
+	s := &n.state
+	switch n.color {
+	case NoisePink:
+		// Paul Kellet's refined pink noise filter
+		s[0] = 0.99886*s[0] + w*0.0555179
+		s[1] = 0.99332*s[1] + w*0.0750759
+		s[2] = 0.96900*s[2] + w*0.1538520
+		s[3] = 0.86650*s[3] + w*0.3104856
+		s[4] = 0.55000*s[4] + w*0.5329522
+		s[5] = -0.7616*s[5] - w*0.0168980
+		v := s[0] + s[1] + s[2] + s[3] + s[4] + s[5] + s[6] + w*0.5362
+		s[6] = w * 0.115926
+		return v * 0.11
+	case NoiseBrown:
+		// leaky integration of white noise
+		s[0] = (s[0] + 0.02*w) / 1.02
+		return s[0] * 3.5
+	}
+	return w
+}
+
+func (n *Noise) reset() {
+	n.next = 0
+	n.last = 0
+	n.state = [7]float64{}
+}
@@ -0,0 +1,189 @@
+package oscillator
+
+import (
+	"math"
+
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// Frequency converts a frequency in Hz to cycles per output sample for the sample rate provided
+func Frequency(hz float64, sampleRate float64) float64 {
+	return hz / sampleRate
+}
+
+// phaseAt returns the phase (0-1) of an oscillator at a position of the stream
+func phaseAt(pos sampling.Pos, frequency float64, phase float64) float64 {
+	return wrap((float64(pos.Pos)+float64(pos.Frac))*frequency + phase)
+}
+
+func mono(v float64) volume.Matrix {
+	return volume.Matrix{
+		StaticMatrix: volume.StaticMatrix{volume.Volume(v)},
+		Channels:     1,
+	}
+}
+
+// polyBLEP returns the polynomial band-limited step correction for an upward step of 2 at phase 0,
+// where `dt` is the phase increment per sample
+func polyBLEP(t float64, dt float64) float64 {
+	switch {
+	case t < dt:
+		t /= dt
+		return t + t - t*t - 1
+	case t > 1-dt:
+		t = (t - 1) / dt
+		return t*t + t + t + 1
+	}
+	return 0
+}
+
+// polyBLAMP returns the polynomial band-limited ramp correction for an increase in slope of 2 per sample
+// at phase 0, where `dt` is the phase increment per sample
+func polyBLAMP(t float64, dt float64) float64 {
+	switch {
+	case t < dt:
+		t = t/dt - 1
+		return -t * t * t / 3
+	case t > 1-dt:
+		t = (t-1)/dt + 1
+		return t * t * t / 3
+	}
+	return 0
+}
+
+// wrap returns the fractional part of a phase, in the range 0 up to but not including 1
+func wrap(t float64) float64 {
+	t -= math.Floor(t)
+	if t >= 1 {
+		// a tiny negative phase rounds up to 1
+		return 0
+	}
+	return t
+}
+
+// Sine is a sine wave oscillator
+type Sine struct {
+	// Frequency is the frequency in cycles per output sample
+	Frequency float64
+	// Phase is the starting phase in cycles (0-1)
+	Phase float64
+	// Amplitude is the peak level of the output
+	Amplitude float64
+}
+
+// NewSine returns a full-scale sine wave oscillator of `frequency` cycles per output sample
+func NewSine(frequency float64) *Sine {
+	return &Sine{
+		Frequency: frequency,
+		Amplitude: 1,
+	}
+}
+
+// GetSample returns the sample at the position provided
+func (o *Sine) GetSample(pos sampling.Pos) volume.Matrix {
+	t := phaseAt(pos, o.Frequency, o.Phase)
+	return mono(o.Amplitude * math.Sin(2*math.Pi*t))
+}
+
+// Saw is a band-limited (PolyBLEP) rising sawtooth wave oscillator
+type Saw struct {
+	// Frequency is the frequency in cycles per output sample
+	Frequency float64
+	// Phase is the starting phase in cycles (0-1)
+	Phase float64
+	// Amplitude is the peak level of the output
+	Amplitude float64
+}
+
+// NewSaw returns a full-scale sawtooth wave oscillator of `frequency` cycles per output sample
+func NewSaw(frequency float64) *Saw {
+	return &Saw{
+		Frequency: frequency,
+		Amplitude: 1,
+	}
+}
+
+// GetSample returns the sample at the position provided
+func (o *Saw) GetSample(pos sampling.Pos) volume.Matrix {
+	dt := math.Abs(o.Frequency)
+	t := phaseAt(pos, o.Frequency, o.Phase+0.5)
+	v := 2*t - 1 - polyBLEP(t, dt)
+	return mono(o.Amplitude * v)
+}
+
+// Pulse is a band-limited (PolyBLEP) pulse wave oscillator with a variable pulse width.
+// The output has no DC offset, so the shorter half-cycle of an asymmetric pulse peaks below
+// the amplitude
+type Pulse struct {
+	// Frequency is the frequency in cycles per output sample
+	Frequency float64
+	// Phase is the starting phase in cycles (0-1)
+	Phase float64
+	// Amplitude is the peak level of the output
+	Amplitude float64
+	// Width is the fraction of each cycle spent high (0-1). A width of 0.5 is a square wave
+	Width float64
+}
+
+// NewPulse returns a full-scale pulse wave oscillator of `frequency` cycles per output sample
+func NewPulse(frequency float64, width float64) *Pulse {
+	return &Pulse{
+		Frequency: frequency,
+		Amplitude: 1,
+		Width:     width,
+	}
+}
+
+// NewSquare returns a full-scale square wave oscillator of `frequency` cycles per output sample
+func NewSquare(frequency float64) *Pulse {
+	return NewPulse(frequency, 0.5)
+}
+
+// GetSample returns the sample at the position provided
+func (o *Pulse) GetSample(pos sampling.Pos) volume.Matrix {
+	dt := math.Abs(o.Frequency)
+	width := math.Max(0, math.Min(o.Width, 1))
+	t := phaseAt(pos, o.Frequency, o.Phase)
+	v := -1.0
+	if t < width {
+		v = 1
+	}
+	v += polyBLEP(t, dt)
+	v -= polyBLEP(wrap(t-width), dt)
+	// remove the DC offset of an asymmetric pulse, then scale the longer half-cycle back to
+	// the amplitude, as it would otherwise peak at 2*max(width, 1-width)
+	v -= 2*width - 1
+	v /= 2 * math.Max(width, 1-width)
+	return mono(o.Amplitude * v)
+}
+
+// Triangle is a band-limited (PolyBLAMP) triangle wave oscillator
+type Triangle struct {
+	// Frequency is the frequency in cycles per output sample
+	Frequency float64
+	// Phase is the starting phase in cycles (0-1)
+	Phase float64
+	// Amplitude is the peak level of the output
+	Amplitude float64
+}
+
+// NewTriangle returns a full-scale triangle wave oscillator of `frequency` cycles per output sample
+func NewTriangle(frequency float64) *Triangle {
+	return &Triangle{
+		Frequency: frequency,
+		Amplitude: 1,
+	}
+}
+
+// GetSample returns the sample at the position provided
+func (o *Triangle) GetSample(pos sampling.Pos) volume.Matrix {
+	dt := math.Abs(o.Frequency)
+	// start at the zero crossing on the way up, like the sine
+	t := phaseAt(pos, o.Frequency, o.Phase+0.75)
+	v := 4*math.Abs(t-0.5) - 1
+	// the slope changes by 8 per cycle (8*dt per sample) at each corner
+	v -= 4 * dt * polyBLAMP(t, dt)
+	v += 4 * dt * polyBLAMP(wrap(t-0.5), dt)
+	return mono(o.Amplitude * v)
+}
@@ -0,0 +1,142 @@
+package oscillator
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/fft"
+	"github.com/gotracker/gomixing/sampling"
+)
+
+// render returns `n` samples of a sample stream
+func render(ss sampling.SampleStream, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = float64(ss.GetSample(sampling.Pos{Pos: i}).StaticMatrix[0])
+	}
+	return out
+}
+
+// aliasedEnergy returns the energy of a signal that lies away from the harmonics of `freq`
+// (in cycles per sample), which for a periodic waveform can only come from aliasing
+func aliasedEnergy(x []float64, freq float64) float64 {
+	const guard = 4 // bins either side of a harmonic that the Hann window spreads it over
+	n := len(x)
+	data := make([]complex128, n)
+	for i, v := range x {
+		w := 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(n))
+		data[i] = complex(w*v, 0)
+	}
+	fft.NewPlan(n).Forward(data)
+
+	var energy float64
+	for k := 1; k <= n/2; k++ {
+		nearest := math.Round(float64(k)/(freq*float64(n))) * freq * float64(n)
+		if math.Abs(float64(k)-nearest) > guard {
+			energy += real(data[k])*real(data[k]) + imag(data[k])*imag(data[k])
+		}
+	}
+	return energy
+}
+
+func TestPolyBLEPAliasesLessThanNaive(t *testing.T) {
+	const (
+		n    = 8192
+		freq = 0.0173 // about 830Hz at 48kHz, with aliases falling between the harmonics
+	)
+	naiveSaw := make([]float64, n)
+	naiveSquare := make([]float64, n)
+	for i := range naiveSaw {
+		t := wrap(float64(i)*freq + 0.5)
+		naiveSaw[i] = 2*t - 1
+		naiveSquare[i] = -1
+		if wrap(float64(i)*freq) < 0.5 {
+			naiveSquare[i] = 1
+		}
+	}
+
+	tests := []struct {
+		name         string
+		ss           sampling.SampleStream
+		naive        []float64
+		minReduction float64
+	}{
+		{"saw", NewSaw(freq), naiveSaw, 10},
+		{"square", NewSquare(freq), naiveSquare, 10},
+	}
+	for _, tt := range tests {
+		got := aliasedEnergy(render(tt.ss, n), freq)
+		naive := aliasedEnergy(tt.naive, freq)
+		if got*tt.minReduction > naive {
+			t.Errorf("%s: aliased energy %g, want at most 1/%v of the naive waveform's %g", tt.name, got, tt.minReduction, naive)
+		}
+	}
+}
+
+func TestPulseAmplitude(t *testing.T) {
+	const n = 4096
+	for _, width := range []float64{0.1, 0.25, 0.5, 0.75, 0.9} {
+		p := NewPulse(0.01, width)
+		p.Amplitude = 0.5
+		out := render(p, n)
+
+		var peak, sum float64
+		for _, v := range out {
+			peak = math.Max(peak, math.Abs(v))
+			sum += v
+		}
+		if math.Abs(peak-0.5) > 1e-6 {
+			t.Errorf("width %v: peak %v, want 0.5", width, peak)
+		}
+		// 4096 samples is 40.96 cycles, so allow for the partial cycle
+		if mean := sum / n; math.Abs(mean) > 0.01 {
+			t.Errorf("width %v: DC offset %v, want 0", width, mean)
+		}
+	}
+}
+
+func TestSineAndTriangle(t *testing.T) {
+	const freq = 0.01
+	sine := render(NewSine(freq), 100)
+	tri := render(NewTriangle(freq), 100)
+	for _, i := range []int{0, 25, 50, 75} {
+		want := math.Sin(2 * math.Pi * freq * float64(i))
+		if math.Abs(sine[i]-want) > 1e-6 {
+			t.Errorf("sine sample %d: got %v, want %v", i, sine[i], want)
+		}
+		// the triangle follows the sine at its zero crossings and peaks, which are slightly
+		// rounded off by the band-limiting
+		if math.Abs(tri[i]-want) > 4*freq {
+			t.Errorf("triangle sample %d: got %v, want %v", i, tri[i], want)
+		}
+	}
+}
+
+func TestNoiseSameSeedSameOutput(t *testing.T) {
+	for _, color := range []NoiseColor{NoiseWhite, NoisePink, NoiseBrown} {
+		a := render(NewNoise(color, 42), 1000)
+		b := render(NewNoise(color, 42), 1000)
+		c := render(NewNoise(color, 43), 1000)
+		same := true
+		for i := range a {
+			if a[i] != b[i] {
+				t.Fatalf("color %d: sample %d differs with the same seed: %v and %v", color, i, a[i], b[i])
+			}
+			if a[i] != c[i] {
+				same = false
+			}
+		}
+		if same {
+			t.Errorf("color %d: different seeds produced the same output", color)
+		}
+
+		// reading backwards regenerates the same values
+		n := NewNoise(color, 42)
+		render(n, 1000)
+		for _, i := range []int{999, 10, 500} {
+			if got := float64(n.GetSample(sampling.Pos{Pos: i}).StaticMatrix[0]); got != a[i] {
+				t.Errorf("color %d: sample %d read out of order got %v, want %v", color, i, got, a[i])
+			}
+		}
+	}
+}
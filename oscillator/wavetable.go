@@ -0,0 +1,214 @@
+package oscillator
+
+import (
+	"errors"
+	"math"
+
+	"github.com/gotracker/gomixing/fft"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+var (
+	// ErrNoTables is returned when a wavetable is created without any tables
+	ErrNoTables = errors.New("wavetable has no tables")
+	// ErrEmptyTable is returned when a wavetable is created with a table that has no samples
+	ErrEmptyTable = errors.New("wavetable has an empty table")
+)
+
+const (
+	// wavetableSize is the length of each band-limited table; it holds up to wavetableSize/2 harmonics
+	wavetableSize = 2048
+	// wavetableLevels is the number of octave mip-map levels, down to a single harmonic
+	wavetableLevels = 11
+)
+
+// Wavetable is a set of single-cycle waveforms with band-limited versions for each octave
+type Wavetable struct {
+	// tables[t][level] holds table t with no more than wavetableSize/2>>level harmonics
+	tables [][wavetableLevels][]float32
+}
+
+// NewWavetable builds a wavetable from single-cycle waveforms of any length.
+// Each waveform is treated as a stepped (sample-and-hold) cycle, as trackers play short
+// chip waveforms, and is band-limited for each octave so it can be played at any pitch without aliasing.
+func NewWavetable(tables [][]float64) (*Wavetable, error) {
+	if len(tables) == 0 {
+		return nil, ErrNoTables
+	}
+	w := &Wavetable{
+		tables: make([][wavetableLevels][]float32, len(tables)),
+	}
+	plan := fft.NewRealPlan(wavetableSize)
+	for t, data := range tables {
+		if len(data) == 0 {
+			return nil, ErrEmptyTable
+		}
+		harmonics := steppedHarmonics(data, wavetableSize/2)
+		for level := range w.tables[t] {
+			w.tables[t][level] = synthesizeTable(plan, harmonics, wavetableSize/2>>uint(level))
+		}
+	}
+	return w, nil
+}
+
+// Len returns the number of tables in the wavetable
+func (w *Wavetable) Len() int {
+	return len(w.tables)
+}
+
+// steppedHarmonics returns the complex Fourier coefficients of one cycle of a waveform
+// played back as steps, up to harmonic `count`. The DC offset is left out
+func steppedHarmonics(data []float64, count int) []complex128 {
+	n := len(data)
+	var spectrum []complex128
+	if n > 1 && n&(n-1) == 0 {
+		spectrum = make([]complex128, n)
+		for i, v := range data {
+			spectrum[i] = complex(v, 0)
+		}
+		fft.NewPlan(n).Forward(spectrum)
+	} else {
+		spectrum = make([]complex128, n)
+		for k := range spectrum {
+			for i, v := range data {
+				s, c := math.Sincos(-2 * math.Pi * float64(k*i) / float64(n))
+				spectrum[k] += complex(v*c, v*s)
+			}
+		}
+	}
+
+	harmonics := make([]complex128, count+1)
+	for h := 1; h <= count; h++ {
+		// each step is a rectangle one sample wide, which shapes the repeating spectrum
+		// of the samples with a sinc and delays it by half a sample
+		x := math.Pi * float64(h) / float64(n)
+		s, c := math.Sincos(-x)
+		harmonics[h] = spectrum[h%n] / complex(float64(n), 0) * complex(math.Sin(x)/x, 0) * complex(c, s)
+	}
+	return harmonics
+}
+
+// synthesizeTable returns a table of the waveform with the harmonics up to `limit`, plus a
+// guard sample for interpolation
+func synthesizeTable(plan *fft.RealPlan, harmonics []complex128, limit int) []float32 {
+	bins := make([]complex128, plan.Bins())
+	for h := 1; h <= limit && h < len(harmonics) && h < len(bins)-1; h++ {
+		bins[h] = harmonics[h] * complex(wavetableSize, 0)
+	}
+	data := make([]float64, wavetableSize)
+	plan.Inverse(bins, data)
+
+	table := make([]float32, wavetableSize+1)
+	for i, v := range data {
+		table[i] = float32(v)
+	}
+	table[wavetableSize] = table[0]
+	return table
+}
+
+// level returns the mip-map level with no harmonics above the Nyquist frequency
+// for a frequency in cycles per output sample
+func (w *Wavetable) level(frequency float64) int {
+	frequency = math.Abs(frequency)
+	if frequency <= 0 {
+		return 0
+	}
+	allowed := 0.5 / frequency
+	level := int(math.Ceil(math.Log2(float64(wavetableSize/2) / allowed)))
+	if level < 0 {
+		return 0
+	} else if level >= wavetableLevels {
+		return wavetableLevels - 1
+	}
+	return level
+}
+
+// sample returns the value of the wavetable at a phase (0-1), morphing between tables
+// by a position (0-1) and band-limited for the frequency provided
+func (w *Wavetable) sample(phase float64, position float64, frequency float64) float64 {
+	level := w.level(frequency)
+	x := phase * wavetableSize
+	i := int(x)
+	if i >= wavetableSize {
+		i = wavetableSize - 1
+	}
+	frac := x - float64(i)
+	read := func(t int) float64 {
+		table := w.tables[t][level]
+		a := float64(table[i])
+		return a + (float64(table[i+1])-a)*frac
+	}
+
+	position = math.Max(0, math.Min(position, 1)) * float64(len(w.tables)-1)
+	t := int(position)
+	if t >= len(w.tables)-1 {
+		return read(len(w.tables) - 1)
+	}
+	mix := position - float64(t)
+	a := read(t)
+	if mix == 0 {
+		return a
+	}
+	return a + (read(t+1)-a)*mix
+}
+
+// WavetableStream plays a wavetable as a sample stream
+type WavetableStream struct {
+	// Frequency is the frequency in cycles per output sample
+	Frequency float64
+	// Phase is the starting phase in cycles (0-1)
+	Phase float64
+	// Amplitude is the peak level of the output
+	Amplitude float64
+	// Position selects the table to play (0-1), crossfading between neighboring tables
+	Position float64
+
+	table *Wavetable
+}
+
+// NewWavetableStream returns a stream that plays the wavetable at `frequency` cycles per output sample.
+// As a SampleStream, the phase is worked out from the stream position, so a sampler should advance by
+// one position per output sample. Use Sampler to play it with a phase that follows frequency changes.
+func NewWavetableStream(table *Wavetable, frequency float64) *WavetableStream {
+	return &WavetableStream{
+		Frequency: frequency,
+		Amplitude: 1,
+		table:     table,
+	}
+}
+
+// GetSample returns the sample at the position provided
+func (s *WavetableStream) GetSample(pos sampling.Pos) volume.Matrix {
+	t := phaseAt(pos, s.Frequency, s.Phase)
+	return mono(s.Amplitude * s.table.sample(t, s.Position, s.Frequency))
+}
+
+// Sampler returns a sampler that plays the stream with its own phase accumulator,
+// so changes to the stream's Frequency bend the pitch without jumps in phase
+func (s *WavetableStream) Sampler() sampling.Sampler {
+	return &wavetableSampler{
+		stream: s,
+		phase:  wrap(s.Phase),
+	}
+}
+
+type wavetableSampler struct {
+	stream *WavetableStream
+	phase  float64
+	pos    sampling.Pos
+}
+
+func (w *wavetableSampler) GetPosition() sampling.Pos {
+	return w.pos
+}
+
+func (w *wavetableSampler) Advance() {
+	w.phase = wrap(w.phase + w.stream.Frequency)
+	w.pos.Add(1)
+}
+
+func (w *wavetableSampler) GetSample() volume.Matrix {
+	s := w.stream
+	return mono(s.Amplitude * s.table.sample(w.phase, s.Position, s.Frequency))
+}
@@ -0,0 +1,139 @@
+package oscillator
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/fft"
+)
+
+// stepped returns a zero-mean waveform of `n` steps: a rising ramp or a square
+func stepped(n int, square bool) []float64 {
+	data := make([]float64, n)
+	for i := range data {
+		if square {
+			data[i] = -1
+			if i < n/2 {
+				data[i] = 1
+			}
+			continue
+		}
+		data[i] = 2*(float64(i)+0.5)/float64(n) - 1
+	}
+	return data
+}
+
+func newTestWavetable(t *testing.T) *Wavetable {
+	t.Helper()
+	w, err := NewWavetable([][]float64{stepped(32, false), stepped(32, true)})
+	if err != nil {
+		t.Fatalf("NewWavetable: %v", err)
+	}
+	return w
+}
+
+func TestNewWavetableInvalid(t *testing.T) {
+	if _, err := NewWavetable(nil); !errors.Is(err, ErrNoTables) {
+		t.Errorf("no tables: got %v, want %v", err, ErrNoTables)
+	}
+	if _, err := NewWavetable([][]float64{{1, -1}, {}}); !errors.Is(err, ErrEmptyTable) {
+		t.Errorf("empty table: got %v, want %v", err, ErrEmptyTable)
+	}
+}
+
+func TestWavetableLevelBelowNyquist(t *testing.T) {
+	w := newTestWavetable(t)
+	for f := 0.0001; f <= 0.5; f *= 1.01 {
+		level := w.level(f)
+		harmonics := wavetableSize / 2 >> uint(level)
+		if float64(harmonics)*f > 0.5 {
+			t.Errorf("frequency %v: level %d plays %d harmonics, the highest above the Nyquist frequency", f, level, harmonics)
+		}
+		// the next finer level would alias, so no more harmonics are dropped than needed
+		if level > 0 && float64(harmonics*2)*f <= 0.5 {
+			t.Errorf("frequency %v: level %d drops harmonics that fit below the Nyquist frequency", f, level)
+		}
+		if w.level(-f) != level {
+			t.Errorf("frequency %v: negative frequency uses level %d, want %d", f, w.level(-f), level)
+		}
+	}
+}
+
+func TestWavetableLevelsAreBandLimited(t *testing.T) {
+	w := newTestWavetable(t)
+	plan := fft.NewRealPlan(wavetableSize)
+	bins := make([]complex128, plan.Bins())
+	for tbl := range w.tables {
+		for level, table := range w.tables[tbl] {
+			data := make([]float64, wavetableSize)
+			for i := range data {
+				data[i] = float64(table[i])
+			}
+			plan.Forward(data, bins)
+
+			limit := wavetableSize / 2 >> uint(level)
+			var inBand, outOfBand float64
+			for k, b := range bins {
+				e := real(b)*real(b) + imag(b)*imag(b)
+				if k <= limit {
+					inBand += e
+				} else {
+					outOfBand += e
+				}
+			}
+			if outOfBand > inBand*1e-9 {
+				t.Errorf("table %d level %d: energy %g above harmonic %d, against %g below", tbl, level, outOfBand, limit, inBand)
+			}
+			if table[wavetableSize] != table[0] {
+				t.Errorf("table %d level %d: guard sample %v, want %v", tbl, level, table[wavetableSize], table[0])
+			}
+		}
+	}
+}
+
+func TestWavetableMorphEndpoints(t *testing.T) {
+	sources := [][]float64{stepped(32, false), stepped(32, true)}
+	w := newTestWavetable(t)
+	const freq = 0.0001 // plays every harmonic
+	for step := 0; step < 32; step++ {
+		// the middle of each step, away from the ringing at its edges
+		phase := (float64(step) + 0.5) / 32
+		for tbl, position := range []float64{0, 1} {
+			if got, want := w.sample(phase, position, freq), sources[tbl][step]; math.Abs(got-want) > 0.02 {
+				t.Errorf("position %v step %d: got %v, want %v", position, step, got, want)
+			}
+		}
+		a := w.sample(phase, 0, freq)
+		b := w.sample(phase, 1, freq)
+		if got, want := w.sample(phase, 0.5, freq), (a+b)/2; math.Abs(got-want) > 1e-9 {
+			t.Errorf("halfway through step %d: got %v, want %v", step, got, want)
+		}
+		if got := w.sample(phase, 2, freq); got != b {
+			t.Errorf("position past the end at step %d: got %v, want %v", step, got, b)
+		}
+	}
+}
+
+func TestWavetableSamplerPhaseWraps(t *testing.T) {
+	w := newTestWavetable(t)
+	for _, freq := range []float64{0.01, -0.01, 0.37, -0.37, 3.25, -7.1} {
+		s := NewWavetableStream(w, freq)
+		s.Phase = 0.2
+		ws := s.Sampler().(*wavetableSampler)
+		for i := 0; i < 1000; i++ {
+			if ws.phase < 0 || ws.phase >= 1 {
+				t.Fatalf("frequency %v: phase %v out of range after %d samples", freq, ws.phase, i)
+			}
+			want := wrap(0.2 + float64(i)*freq)
+			if d := math.Abs(ws.phase - want); d > 1e-9 && d < 1-1e-9 {
+				t.Fatalf("frequency %v: phase %v after %d samples, want %v", freq, ws.phase, i, want)
+			}
+			got := ws.GetSample().StaticMatrix[0]
+			if stream := s.GetSample(ws.GetPosition()).StaticMatrix[0]; math.Abs(float64(got-stream)) > 1e-4 {
+				t.Fatalf("frequency %v sample %d: sampler got %v, stream got %v", freq, i, got, stream)
+			}
+			ws.Advance()
+		}
+	}
+}
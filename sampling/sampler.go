@@ -23,3 +23,21 @@ func NewSampler(ss SampleStream, pos Pos, period float32) Sampler {
 	}
 	return &s
 }
+
+// Lengther is an optional interface a SampleStream may implement to report its
+// length in samples, so that samplers playing it can report reaching its end
+type Lengther interface {
+	Len() int
+}
+
+// Ender is an optional interface a Sampler may implement to report that
+// it has reached the end of its sample data
+type Ender interface {
+	IsEnded() bool
+}
+
+// Releaser is an optional interface a Sampler may implement to be notified
+// of a key-off (note-off), such as for exiting a sustain loop
+type Releaser interface {
+	Release()
+}
@@ -22,3 +22,15 @@ func (s *sampler) GetSample() volume.Matrix {
 	}
 	return s.ss.GetSample(s.pos)
 }
+
+// IsEnded returns true once the position has moved off either end of a stream that reports its length
+func (s *sampler) IsEnded() bool {
+	if s.ss == nil {
+		return true
+	}
+	l, ok := s.ss.(Lengther)
+	if !ok {
+		return false
+	}
+	return s.pos.Pos < 0 || s.pos.Pos >= l.Len()
+}
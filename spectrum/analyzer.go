@@ -0,0 +1,327 @@
+package spectrum
+
+import (
+	"fmt"
+	"math"
+	"sync"
+
+	"github.com/gotracker/gomixing/fft"
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// MaxChannels is the largest number of channels an analyzer can measure
+const MaxChannels = volume.MaxChannels
+
+// Scale is the scale of the magnitudes produced by an analyzer
+type Scale int
+
+const (
+	// ScaleLinear produces linear amplitudes, where a full-scale sinusoid reads 1
+	ScaleLinear = Scale(iota)
+	// ScaleDB produces amplitudes in dBFS
+	ScaleDB
+)
+
+// MinDB is the lowest level produced by an analyzer in dB scale
+const MinDB = -144.0
+
+// Analyzer computes the magnitude spectrum of the most recent block of a signal.
+// The settings are read under the same lock as the spectra, so the spectra can be
+// read from another goroutine than the one analyzing the signal
+type Analyzer struct {
+	// Scale is the scale of the magnitudes returned by Spectrum
+	Scale Scale
+	// Smoothing is the amount each spectrum is averaged with the previous ones (0-1)
+	Smoothing float64
+	// Bands is the number of log-spaced frequency bands to group the bins into. Zero returns the bins as-is
+	Bands int
+	// MinFrequency is the lowest frequency in Hz of the log-spaced bands
+	MinFrequency float64
+	// MaxFrequency is the highest frequency in Hz of the log-spaced bands
+	MaxFrequency float64
+
+	sampleRate float64
+	size       int
+	plan       *fft.RealPlan
+	window     []float64
+	scale      float64
+	history    [volume.MaxChannels][]float64
+	pos        int
+	block      []float64
+	bins       []complex128
+
+	mu       sync.Mutex
+	channels int
+	mags     [volume.MaxChannels][]float64
+}
+
+// NewAnalyzer returns an analyzer for the sample rate provided that transforms blocks of
+// `size` samples (rounded up to a power of two) shaped by the window provided
+func NewAnalyzer(sampleRate float64, size int, window fft.Window) *Analyzer {
+	size = fft.NextPowerOfTwo(size)
+	if size < 2 {
+		size = 2
+	}
+	a := &Analyzer{
+		MinFrequency: 20,
+		MaxFrequency: sampleRate / 2,
+		sampleRate:   sampleRate,
+		size:         size,
+		plan:         fft.NewRealPlan(size),
+		window:       window.Coefficients(size),
+		block:        make([]float64, size),
+		bins:         make([]complex128, size/2+1),
+	}
+
+	// normalize by the window's coherent gain so that a full-scale sinusoid reads 1
+	var sum float64
+	for _, w := range a.window {
+		sum += w
+	}
+	a.scale = 2 / sum
+
+	for c := range a.history {
+		a.history[c] = make([]float64, size)
+		a.mags[c] = make([]float64, size/2+1)
+	}
+	return a
+}
+
+// Size returns the number of samples in each transformed block
+func (a *Analyzer) Size() int {
+	return a.size
+}
+
+// Analyze adds a block of the mix buffer to the analyzer and updates the spectra
+func (a *Analyzer) Analyze(buf mixing.MixBuffer) {
+	channels := 0
+	for _, samp := range buf {
+		if samp.Channels > channels {
+			channels = samp.Channels
+		}
+		for c := 0; c < volume.MaxChannels; c++ {
+			var v float64
+			if c < samp.Channels {
+				v = float64(samp.StaticMatrix[c])
+			}
+			a.history[c][a.pos] = v
+		}
+		a.advance()
+	}
+	a.update(channels)
+}
+
+// AnalyzePlanar adds a block of per-channel data to the analyzer and updates the spectra
+func (a *Analyzer) AnalyzePlanar(data [][]volume.Volume) {
+	channels := len(data)
+	if channels > volume.MaxChannels {
+		channels = volume.MaxChannels
+	}
+	n := 0
+	for _, d := range data[:channels] {
+		if len(d) > n {
+			n = len(d)
+		}
+	}
+	for i := 0; i < n; i++ {
+		for c := 0; c < volume.MaxChannels; c++ {
+			var v float64
+			if c < channels && i < len(data[c]) {
+				v = float64(data[c][i])
+			}
+			a.history[c][a.pos] = v
+		}
+		a.advance()
+	}
+	a.update(channels)
+}
+
+func (a *Analyzer) advance() {
+	a.pos++
+	if a.pos >= a.size {
+		a.pos = 0
+	}
+}
+
+// update transforms the most recent block of each channel
+func (a *Analyzer) update(channels int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if channels > a.channels {
+		a.channels = channels
+	}
+	smoothing := math.Max(0, math.Min(a.Smoothing, 1))
+	for c := 0; c < channels; c++ {
+		h := a.history[c]
+		for i := range a.block {
+			a.block[i] = h[(a.pos+i)%a.size] * a.window[i]
+		}
+		a.plan.Forward(a.block, a.bins)
+
+		mags := a.mags[c]
+		last := len(mags) - 1
+		for k, v := range a.bins {
+			m := math.Hypot(real(v), imag(v)) * a.scale
+			if k == 0 || k == last {
+				// DC and Nyquist have no mirror image to fold in
+				m /= 2
+			}
+			mags[k] = mags[k]*smoothing + m*(1-smoothing)
+		}
+	}
+}
+
+// Channels returns the number of channels that have been analyzed
+func (a *Analyzer) Channels() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.channels
+}
+
+// Spectrum returns the magnitude spectrum of a channel, either one value per bin or one value per band,
+// or nil if the channel is out of range. It may be called from any goroutine
+func (a *Analyzer) Spectrum(ch int) []float64 {
+	if ch < 0 || ch >= MaxChannels {
+		return nil
+	}
+
+	a.mu.Lock()
+	var mags []float64
+	if a.Bands > 0 {
+		mags = a.bandMagnitudes(a.mags[ch])
+	} else {
+		mags = append([]float64(nil), a.mags[ch]...)
+	}
+	scale := a.Scale
+	a.mu.Unlock()
+
+	if scale == ScaleDB {
+		for i, m := range mags {
+			mags[i] = math.Max(volume.ToDB(m), MinDB)
+		}
+	}
+	return mags
+}
+
+// Frequencies returns the center frequency in Hz of each value returned by Spectrum
+func (a *Analyzer) Frequencies() []float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.Bands > 0 {
+		edges := a.bandEdges()
+		freqs := make([]float64, a.Bands)
+		for b := range freqs {
+			freqs[b] = math.Sqrt(edges[b] * edges[b+1])
+		}
+		return freqs
+	}
+	freqs := make([]float64, a.size/2+1)
+	for k := range freqs {
+		freqs[k] = a.binFrequency(float64(k))
+	}
+	return freqs
+}
+
+func (a *Analyzer) binFrequency(k float64) float64 {
+	return k * a.sampleRate / float64(a.size)
+}
+
+// bandEdges returns the Bands+1 log-spaced band edges in Hz
+func (a *Analyzer) bandEdges() []float64 {
+	nyquist := a.sampleRate / 2
+	lo := math.Max(a.MinFrequency, a.binFrequency(1)/2)
+	hi := math.Min(math.Max(a.MaxFrequency, lo), nyquist)
+	edges := make([]float64, a.Bands+1)
+	for b := range edges {
+		edges[b] = lo * math.Pow(hi/lo, float64(b)/float64(a.Bands))
+	}
+	return edges
+}
+
+// bandMagnitudes groups bins into log-spaced bands, taking the largest bin of each band.
+// Bands narrower than a bin are interpolated from the neighboring bins
+func (a *Analyzer) bandMagnitudes(mags []float64) []float64 {
+	edges := a.bandEdges()
+	binWidth := a.binFrequency(1)
+	bands := make([]float64, a.Bands)
+	for b := range bands {
+		first := int(math.Ceil(edges[b] / binWidth))
+		last := int(math.Ceil(edges[b+1]/binWidth)) - 1
+		if last >= len(mags) {
+			last = len(mags) - 1
+		}
+		if first <= last {
+			for k := first; k <= last; k++ {
+				bands[b] = math.Max(bands[b], mags[k])
+			}
+			continue
+		}
+		pos := math.Sqrt(edges[b]*edges[b+1]) / binWidth
+		k := int(pos)
+		if k >= len(mags)-1 {
+			bands[b] = mags[len(mags)-1]
+			continue
+		}
+		t := pos - float64(k)
+		bands[b] = mags[k] + (mags[k+1]-mags[k])*t
+	}
+	return bands
+}
+
+// Process analyzes the mix buffer without changing it, so the analyzer can be used as an effect
+func (a *Analyzer) Process(buf mixing.MixBuffer) {
+	a.Analyze(buf)
+}
+
+// Reset clears the signal history and the spectra
+func (a *Analyzer) Reset() {
+	for c := range a.history {
+		for i := range a.history[c] {
+			a.history[c][i] = 0
+		}
+	}
+	a.pos = 0
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for c := range a.mags {
+		for i := range a.mags[c] {
+			a.mags[c][i] = 0
+		}
+	}
+	a.channels = 0
+}
+
+// Latency returns the number of samples the analyzer delays its output by
+func (a *Analyzer) Latency() int {
+	return 0
+}
+
+// Parameters returns the descriptions of the parameters of the analyzer
+func (a *Analyzer) Parameters() []mixing.ParameterInfo {
+	return []mixing.ParameterInfo{
+		{Name: "smoothing", Min: 0, Max: 1, Default: 0},
+	}
+}
+
+// GetParameter returns the current value of a parameter
+func (a *Analyzer) GetParameter(name string) (float64, error) {
+	if name == "smoothing" {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		return a.Smoothing, nil
+	}
+	return 0, fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, "analyzer", name)
+}
+
+// SetParameter sets the value of a parameter
+func (a *Analyzer) SetParameter(name string, value float64) error {
+	if name == "smoothing" {
+		a.mu.Lock()
+		a.Smoothing = math.Max(0, math.Min(value, 1))
+		a.mu.Unlock()
+		return nil
+	}
+	return fmt.Errorf("%w: %s has no parameter %q", mixing.ErrUnknownParameter, "analyzer", name)
+}
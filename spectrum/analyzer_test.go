@@ -0,0 +1,125 @@
+package spectrum
+
+import (
+	"math"
+	"testing"
+
+	"github.com/gotracker/gomixing/fft"
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// sine returns a stereo mix buffer holding a sine at `freq` Hz in the first channel and silence in the second
+func sine(sampleRate float64, freq float64, amplitude float64, n int) mixing.MixBuffer {
+	buf := make(mixing.MixBuffer, n)
+	for i := range buf {
+		v := volume.Volume(amplitude * math.Sin(2*math.Pi*freq*float64(i)/sampleRate))
+		buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{v, 0}, Channels: 2}
+	}
+	return buf
+}
+
+func TestAnalyzerFullScaleSine(t *testing.T) {
+	const (
+		sampleRate = 48000
+		size       = 1024
+		bin        = 64
+	)
+	freq := float64(bin) * sampleRate / size
+	windows := []fft.Window{fft.WindowRectangular, fft.WindowHann, fft.WindowBlackmanHarris, fft.WindowFlatTop}
+	for _, w := range windows {
+		a := NewAnalyzer(sampleRate, size, w)
+		a.Analyze(sine(sampleRate, freq, 1, size))
+
+		mags := a.Spectrum(0)
+		if len(mags) != size/2+1 {
+			t.Fatalf("window %d: got %d bins, want %d", w, len(mags), size/2+1)
+		}
+		peak := 0
+		for k := range mags {
+			if mags[k] > mags[peak] {
+				peak = k
+			}
+		}
+		if peak != bin {
+			t.Errorf("window %d: peak in bin %d, want %d", w, peak, bin)
+		}
+		if math.Abs(mags[bin]-1) > 1e-3 {
+			t.Errorf("window %d: bin %d got %v, want 1", w, bin, mags[bin])
+		}
+		if got := a.Frequencies()[bin]; got != freq {
+			t.Errorf("window %d: frequency of bin %d got %v, want %v", w, bin, got, freq)
+		}
+
+		a.Scale = ScaleDB
+		if got := a.Spectrum(0)[bin]; math.Abs(got) > 0.01 {
+			t.Errorf("window %d: bin %d got %v dBFS, want 0", w, bin, got)
+		}
+		if got := a.Spectrum(1)[bin]; got != MinDB {
+			t.Errorf("window %d: silent channel got %v dBFS, want %v", w, got, MinDB)
+		}
+	}
+}
+
+func TestAnalyzerDC(t *testing.T) {
+	a := NewAnalyzer(48000, 256, fft.WindowHann)
+	buf := make(mixing.MixBuffer, 256)
+	for i := range buf {
+		buf[i] = volume.Matrix{StaticMatrix: volume.StaticMatrix{0.5}, Channels: 1}
+	}
+	a.Analyze(buf)
+	if got := a.Spectrum(0)[0]; math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("DC bin: got %v, want 0.5", got)
+	}
+}
+
+func TestAnalyzerBands(t *testing.T) {
+	const (
+		sampleRate = 48000
+		size       = 4096
+	)
+	a := NewAnalyzer(sampleRate, size, fft.WindowHann)
+	a.Bands = 10
+	a.MinFrequency = 20
+	a.MaxFrequency = 20000
+
+	freqs := a.Frequencies()
+	if len(freqs) != a.Bands {
+		t.Fatalf("band frequencies: got %d, want %d", len(freqs), a.Bands)
+	}
+	// the bands are evenly spaced on a log scale
+	ratio := math.Pow(20000.0/20, 1.0/10)
+	if got := freqs[0]; math.Abs(got-20*math.Sqrt(ratio)) > 1e-9 {
+		t.Errorf("first band center: got %v, want %v", got, 20*math.Sqrt(ratio))
+	}
+	for b := 1; b < len(freqs); b++ {
+		if got := freqs[b] / freqs[b-1]; math.Abs(got-ratio) > 1e-9 {
+			t.Errorf("band %d spacing: got %v, want %v", b, got, ratio)
+		}
+	}
+
+	// a bin-centred sine lands at full scale in the band whose edges contain it
+	freq := 256.0 * sampleRate / size
+	a.Analyze(sine(sampleRate, freq, 1, size))
+	bands := a.Spectrum(0)
+	want := int(math.Log(freq/20) / math.Log(ratio))
+	for b, m := range bands {
+		switch {
+		case b == want:
+			if math.Abs(m-1) > 1e-3 {
+				t.Errorf("band %d holding %v Hz: got %v, want 1", b, freq, m)
+			}
+		case m > 0.01:
+			t.Errorf("band %d away from %v Hz: got %v, want about 0", b, freq, m)
+		}
+	}
+}
+
+func TestAnalyzerChannelOutOfRange(t *testing.T) {
+	a := NewAnalyzer(48000, 256, fft.WindowHann)
+	for _, ch := range []int{-1, MaxChannels} {
+		if got := a.Spectrum(ch); got != nil {
+			t.Errorf("Spectrum(%d): got %v, want nil", ch, got)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package volume
+
+import "math"
+
+// ClipMode is the method used to keep volumes within the -1.0 to 1.0 range of the output
+type ClipMode uint8
+
+const (
+	// ClipHard clamps volumes to the range
+	ClipHard = ClipMode(iota)
+	// ClipTanh saturates volumes with a hyperbolic tangent curve
+	ClipTanh
+	// ClipCubic saturates volumes with a cubic soft-knee curve
+	ClipCubic
+	// ClipPolynomial saturates volumes with a fifth-order curve, which stays linear longer than ClipCubic
+	ClipPolynomial
+)
+
+// Clip returns the volume limited to the -1.0 to 1.0 range using the clipping mode provided
+func (v Volume) Clip(mode ClipMode) Volume {
+	x := float64(v)
+	switch mode {
+	case ClipTanh:
+		return Volume(math.Tanh(x))
+	case ClipCubic:
+		if math.Abs(x) >= 1 {
+			return Volume(math.Copysign(1, x))
+		}
+		return Volume(1.5*x - 0.5*x*x*x)
+	case ClipPolynomial:
+		if math.Abs(x) >= 1 {
+			return Volume(math.Copysign(1, x))
+		}
+		x2 := x * x
+		return Volume(1.25 * (x - x2*x2*x/5))
+	default:
+		return Volume(v.WithOverflowProtection())
+	}
+}
@@ -0,0 +1,28 @@
+package volume
+
+import "math"
+
+// MaxChannels is the largest number of channels a Matrix can hold
+const MaxChannels = len(StaticMatrix{})
+
+// ToDB converts a linear amplitude to decibels. Silence is negative infinity
+func ToDB(v float64) float64 {
+	if v <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(v)
+}
+
+// FromDB converts decibels to a linear amplitude
+func FromDB(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// SmoothingCoeff returns the one-pole smoothing coefficient for a time constant in milliseconds.
+// A time constant of zero or less returns 0, which follows the input immediately
+func SmoothingCoeff(ms float64, sampleRate float64) float64 {
+	if ms <= 0 {
+		return 0
+	}
+	return math.Exp(-1 / (ms * sampleRate / 1000))
+}
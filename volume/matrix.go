@@ -162,3 +162,11 @@ func (m Matrix) Lerp(other Matrix, t float32) Matrix {
 	}
 	return out
 }
+
+// Clip limits every channel of the matrix to the -1.0 to 1.0 range using the clipping mode provided
+func (m Matrix) Clip(mode ClipMode) Matrix {
+	for i := 0; i < m.Channels; i++ {
+		m.StaticMatrix[i] = m.StaticMatrix[i].Clip(mode)
+	}
+	return m
+}
@@ -0,0 +1,252 @@
+package waveform
+
+import (
+	"errors"
+	"math"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/sampling"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// ErrUnsupportedFormat is returned when PCM data is in a format that has no formatter
+var ErrUnsupportedFormat = errors.New("unsupported sample format")
+
+const (
+	// MaxChannels is the largest number of channels an overview can hold
+	MaxChannels = volume.MaxChannels
+	// baseBlockSize is the number of frames summarized by each point of the finest summary level
+	baseBlockSize = 16
+)
+
+// Point summarizes a range of samples of one channel
+type Point struct {
+	Min volume.Volume
+	Max volume.Volume
+	RMS volume.Volume
+}
+
+// summary is a mergeable summary of a block of samples
+type summary struct {
+	min, max volume.Volume
+	sumSq    float64
+	n        int
+}
+
+func (s *summary) addSample(v volume.Volume) {
+	if s.n == 0 || v < s.min {
+		s.min = v
+	}
+	if s.n == 0 || v > s.max {
+		s.max = v
+	}
+	s.sumSq += float64(v) * float64(v)
+	s.n++
+}
+
+func (s *summary) merge(o summary) {
+	if o.n == 0 {
+		return
+	}
+	if s.n == 0 || o.min < s.min {
+		s.min = o.min
+	}
+	if s.n == 0 || o.max > s.max {
+		s.max = o.max
+	}
+	s.sumSq += o.sumSq
+	s.n += o.n
+}
+
+func (s summary) point() Point {
+	if s.n == 0 {
+		return Point{}
+	}
+	return Point{
+		Min: s.min,
+		Max: s.max,
+		RMS: volume.Volume(math.Sqrt(s.sumSq / float64(s.n))),
+	}
+}
+
+// Overview is a multi-resolution min/max/RMS summary of a waveform for drawing at any zoom level.
+// It is built once from its source and is safe to query from multiple goroutines.
+type Overview struct {
+	channels int
+	length   int
+	samples  [][]volume.Volume
+	// levels[i][ch] summarizes blocks of baseBlockSize<<i frames
+	levels [][][]summary
+}
+
+// NewOverviewFromSampleStream builds an overview of the first `length` frames of a sample stream
+func NewOverviewFromSampleStream(ss sampling.SampleStream, channels int, length int) *Overview {
+	o := newOverview(channels, length)
+	for i := 0; i < length; i++ {
+		o.setFrame(i, ss.GetSample(sampling.Pos{Pos: i}))
+	}
+	o.build()
+	return o
+}
+
+// NewOverviewFromMixBuffer builds an overview of a rendered mix buffer
+func NewOverviewFromMixBuffer(buf mixing.MixBuffer, channels int) *Overview {
+	o := newOverview(channels, len(buf))
+	for i, samp := range buf {
+		o.setFrame(i, samp)
+	}
+	o.build()
+	return o
+}
+
+// NewOverviewFromPCM builds an overview of interleaved PCM data in the format provided
+func NewOverviewFromPCM(data []byte, format sampling.Format, channels int) (*Overview, error) {
+	formatter := sampling.GetFormatter(format)
+	if formatter == nil {
+		return nil, ErrUnsupportedFormat
+	}
+	if channels <= 0 {
+		channels = 1
+	}
+	frameSize := formatter.Size() * channels
+	o := newOverview(channels, len(data)/frameSize)
+	for i := 0; i < o.length; i++ {
+		samp := volume.Matrix{Channels: channels}
+		for c := 0; c < channels; c++ {
+			v, err := formatter.ReadAt(data, int64(i*frameSize+c*formatter.Size()))
+			if err != nil {
+				return nil, err
+			}
+			samp.StaticMatrix[c] = v
+		}
+		o.setFrame(i, samp)
+	}
+	o.build()
+	return o, nil
+}
+
+func newOverview(channels int, length int) *Overview {
+	if channels <= 0 {
+		channels = 1
+	} else if channels > volume.MaxChannels {
+		channels = volume.MaxChannels
+	}
+	o := &Overview{
+		channels: channels,
+		length:   length,
+		samples:  make([][]volume.Volume, channels),
+	}
+	for c := range o.samples {
+		o.samples[c] = make([]volume.Volume, length)
+	}
+	return o
+}
+
+func (o *Overview) setFrame(i int, samp volume.Matrix) {
+	if samp.Channels == 0 {
+		return
+	}
+	d := samp.ToChannels(o.channels)
+	for c := 0; c < o.channels; c++ {
+		o.samples[c][i] = d.StaticMatrix[c]
+	}
+}
+
+// build summarizes the samples into blocks of baseBlockSize frames, then halves the
+// resolution of each level until a single block covers the whole waveform
+func (o *Overview) build() {
+	blocks := (o.length + baseBlockSize - 1) / baseBlockSize
+	if blocks == 0 {
+		return
+	}
+	base := make([][]summary, o.channels)
+	for c := range base {
+		base[c] = make([]summary, blocks)
+		for i, v := range o.samples[c] {
+			base[c][i/baseBlockSize].addSample(v)
+		}
+	}
+	o.levels = append(o.levels, base)
+
+	for blocks > 1 {
+		prev := o.levels[len(o.levels)-1]
+		blocks = (blocks + 1) / 2
+		next := make([][]summary, o.channels)
+		for c := range next {
+			next[c] = make([]summary, blocks)
+			for i, s := range prev[c] {
+				next[c][i/2].merge(s)
+			}
+		}
+		o.levels = append(o.levels, next)
+	}
+}
+
+// Channels returns the number of channels in the overview
+func (o *Overview) Channels() int {
+	return o.channels
+}
+
+// Len returns the length of the overview in frames
+func (o *Overview) Len() int {
+	return o.length
+}
+
+// Summarize returns the summary of the frames of a channel from `start` up to but not including `end`
+func (o *Overview) Summarize(ch int, start int, end int) Point {
+	return o.summarize(ch, start, end).point()
+}
+
+// Query divides the frames of a channel from `start` up to but not including `end` into
+// `pixels` equal ranges and returns the summary of each
+func (o *Overview) Query(ch int, start int, end int, pixels int) []Point {
+	if pixels <= 0 {
+		return nil
+	}
+	points := make([]Point, pixels)
+	span := float64(end-start) / float64(pixels)
+	for p := range points {
+		from := start + int(math.Floor(float64(p)*span))
+		to := start + int(math.Floor(float64(p+1)*span))
+		if to <= from {
+			to = from + 1
+		}
+		points[p] = o.summarize(ch, from, to).point()
+	}
+	return points
+}
+
+// summarize combines the frames from `start` up to `end`, using the largest summary
+// blocks that fit in the range and the raw samples at its edges
+func (o *Overview) summarize(ch int, start int, end int) summary {
+	var s summary
+	if ch < 0 || ch >= o.channels {
+		return s
+	}
+	if start < 0 {
+		start = 0
+	}
+	if end > o.length {
+		end = o.length
+	}
+
+	pos := start
+	for pos < end {
+		level := -1
+		for level+1 < len(o.levels) {
+			blockSize := baseBlockSize << uint(level+1)
+			if pos%blockSize != 0 || pos+blockSize > end {
+				break
+			}
+			level++
+		}
+		if level < 0 {
+			s.addSample(o.samples[ch][pos])
+			pos++
+			continue
+		}
+		s.merge(o.levels[level][ch][pos/(baseBlockSize<<uint(level))])
+		pos += baseBlockSize << uint(level)
+	}
+	return s
+}
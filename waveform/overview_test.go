@@ -0,0 +1,137 @@
+package waveform
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/gotracker/gomixing/mixing"
+	"github.com/gotracker/gomixing/volume"
+)
+
+// randomBuffer returns a stereo mix buffer of random samples
+func randomBuffer(rng *rand.Rand, n int) mixing.MixBuffer {
+	buf := make(mixing.MixBuffer, n)
+	for i := range buf {
+		buf[i] = volume.Matrix{
+			StaticMatrix: volume.StaticMatrix{volume.Volume(rng.Float64()*2 - 1), volume.Volume(rng.Float64()*2 - 1)},
+			Channels:     2,
+		}
+	}
+	return buf
+}
+
+// bruteForce summarizes a range of a channel by scanning every sample
+func bruteForce(buf mixing.MixBuffer, ch int, start int, end int) Point {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(buf) {
+		end = len(buf)
+	}
+	if start >= end {
+		return Point{}
+	}
+	p := Point{Min: buf[start].StaticMatrix[ch], Max: buf[start].StaticMatrix[ch]}
+	var sumSq float64
+	for _, samp := range buf[start:end] {
+		v := samp.StaticMatrix[ch]
+		if v < p.Min {
+			p.Min = v
+		}
+		if v > p.Max {
+			p.Max = v
+		}
+		sumSq += float64(v) * float64(v)
+	}
+	p.RMS = volume.Volume(math.Sqrt(sumSq / float64(end-start)))
+	return p
+}
+
+func pointsMatch(got Point, want Point) bool {
+	return got.Min == want.Min && got.Max == want.Max && math.Abs(float64(got.RMS-want.RMS)) < 1e-6
+}
+
+func TestOverviewSummarizeMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, length := range []int{1, 15, 16, 17, 597, 4096, 5000} {
+		buf := randomBuffer(rng, length)
+		o := NewOverviewFromMixBuffer(buf, 2)
+
+		type span struct{ start, end int }
+		var spans []span
+		// ranges covering exactly one block of each level, and the blocks either side of them
+		for level := range o.levels {
+			size := baseBlockSize << uint(level)
+			for _, start := range []int{0, size, 2 * size, size - 1, size + 1} {
+				spans = append(spans, span{start, start + size}, span{start, start + 2*size + 3})
+			}
+		}
+		// unaligned ranges, ranges at the ends of the data and ranges past them
+		spans = append(spans,
+			span{0, length}, span{3, 250}, span{5, 6}, span{length - 1, length},
+			span{length - 20, length}, span{length - 33, length + 10}, span{-5, 40}, span{10, 10},
+		)
+		for i := 0; i < 100; i++ {
+			a, b := rng.Intn(length+1), rng.Intn(length+1)
+			if a > b {
+				a, b = b, a
+			}
+			spans = append(spans, span{a, b})
+		}
+
+		for _, sp := range spans {
+			for ch := 0; ch < 2; ch++ {
+				want := bruteForce(buf, ch, sp.start, sp.end)
+				if got := o.Summarize(ch, sp.start, sp.end); !pointsMatch(got, want) {
+					t.Errorf("length %d channel %d [%d, %d): got %+v, want %+v", length, ch, sp.start, sp.end, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestOverviewQueryMatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	const length = 10000
+	buf := randomBuffer(rng, length)
+	o := NewOverviewFromMixBuffer(buf, 2)
+
+	tests := []struct {
+		start, end, pixels int
+	}{
+		{0, length, 1},
+		{0, length, 7},
+		{0, length, 640},
+		{123, 4567, 100},
+		{length - 50, length, 50},
+		{length - 50, length, 200},
+		{9000, length + 1000, 10},
+	}
+	for _, tt := range tests {
+		points := o.Query(0, tt.start, tt.end, tt.pixels)
+		if len(points) != tt.pixels {
+			t.Fatalf("Query(%d, %d, %d): got %d points", tt.start, tt.end, tt.pixels, len(points))
+		}
+		span := float64(tt.end-tt.start) / float64(tt.pixels)
+		for p, got := range points {
+			from := tt.start + int(math.Floor(float64(p)*span))
+			to := tt.start + int(math.Floor(float64(p+1)*span))
+			if to <= from {
+				to = from + 1
+			}
+			if want := bruteForce(buf, 0, from, to); !pointsMatch(got, want) {
+				t.Errorf("Query(%d, %d, %d) pixel %d: got %+v, want %+v", tt.start, tt.end, tt.pixels, p, got, want)
+			}
+		}
+	}
+}
+
+func TestOverviewChannelOutOfRange(t *testing.T) {
+	o := NewOverviewFromMixBuffer(randomBuffer(rand.New(rand.NewSource(3)), 100), 2)
+	for _, ch := range []int{-1, 2} {
+		if got := o.Summarize(ch, 0, 100); got != (Point{}) {
+			t.Errorf("Summarize(%d): got %+v, want an empty point", ch, got)
+		}
+	}
+}